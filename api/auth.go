@@ -0,0 +1,142 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const urlBitbucketOAuth2Token = "https://bitbucket.org/site/oauth2/access_token"
+
+// Authenticator sets credentials to requests to Bitbucket API.
+type Authenticator interface {
+	Authenticate(ctx context.Context, req *http.Request) error
+}
+
+// BasicAuth authenticates with username and app password.
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+func (a BasicAuth) Authenticate(ctx context.Context, req *http.Request) error {
+	req.SetBasicAuth(a.Username, a.Password)
+	return nil
+}
+
+// BearerToken authenticates with a static token such as API token, repository or workspace access token.
+type BearerToken struct {
+	Token string
+}
+
+func (a BearerToken) Authenticate(ctx context.Context, req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+a.Token)
+	return nil
+}
+
+// OAuth2ClientCredentials authenticates with access token of OAuth consumer obtained by client credentials grant.
+// The token is refreshed when it expires.
+type OAuth2ClientCredentials struct {
+	hc *http.Client
+
+	tokenUrl     string
+	clientId     string
+	clientSecret string
+
+	mu           sync.Mutex
+	accessToken  string
+	refreshToken string
+	expiry       time.Time
+}
+
+func NewOAuth2ClientCredentials(
+	hc *http.Client,
+	clientId string,
+	clientSecret string,
+) *OAuth2ClientCredentials {
+	return &OAuth2ClientCredentials{
+		hc:           hc,
+		tokenUrl:     urlBitbucketOAuth2Token,
+		clientId:     clientId,
+		clientSecret: clientSecret,
+	}
+}
+
+type oauth2TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	TokenType    string `json:"token_type"`
+}
+
+// expiryDelta is how early the token is refreshed before it expires.
+const expiryDelta = 30 * time.Second
+
+func (a *OAuth2ClientCredentials) Authenticate(ctx context.Context, req *http.Request) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.accessToken == "" || time.Now().Add(expiryDelta).After(a.expiry) {
+		if err := a.fetchToken(ctx); err != nil {
+			return err
+		}
+	}
+
+	req.Header.Set("Authorization", "Bearer "+a.accessToken)
+	return nil
+}
+
+// fetchToken gets a new access token, using the refresh token if it has one.
+func (a *OAuth2ClientCredentials) fetchToken(ctx context.Context) error {
+	form := url.Values{}
+	if a.refreshToken != "" {
+		form.Set("grant_type", "refresh_token")
+		form.Set("refresh_token", a.refreshToken)
+	} else {
+		form.Set("grant_type", "client_credentials")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", a.tokenUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(a.clientId, a.clientSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := a.hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode >= 400 {
+		if a.refreshToken != "" {
+			// the refresh token may be revoked. retry with client credentials
+			a.refreshToken = ""
+			return a.fetchToken(ctx)
+		}
+		return fmt.Errorf("failed to get oauth2 token: %v", res.Status)
+	}
+
+	var token oauth2TokenResponse
+	if err := json.Unmarshal(b, &token); err != nil {
+		return err
+	}
+
+	a.accessToken = token.AccessToken
+	a.refreshToken = token.RefreshToken
+	a.expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+
+	return nil
+}
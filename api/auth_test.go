@@ -0,0 +1,44 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBearerToken_Authenticate(t *testing.T) {
+	req, _ := http.NewRequest("GET", "https://api.bitbucket.org/2.0/user", nil)
+	err := BearerToken{Token: "token"}.Authenticate(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer token", req.Header.Get("Authorization"))
+}
+
+func TestOAuth2ClientCredentials_Authenticate(t *testing.T) {
+	grantTypes := make([]string, 0)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		assert.Equal(t, "client", id)
+		assert.Equal(t, "secret", secret)
+		r.ParseForm()
+		grantTypes = append(grantTypes, r.PostForm.Get("grant_type"))
+
+		// expires immediately so that the next request refreshes the token
+		fmt.Fprintf(w, `{"access_token":"token-%d","refresh_token":"refresh","expires_in":0,"token_type":"bearer"}`, len(grantTypes))
+	}))
+	defer ts.Close()
+
+	a := NewOAuth2ClientCredentials(http.DefaultClient, "client", "secret")
+	a.tokenUrl = ts.URL
+
+	for i := 1; i <= 2; i++ {
+		req, _ := http.NewRequest("GET", "https://api.bitbucket.org/2.0/user", nil)
+		err := a.Authenticate(context.Background(), req)
+		assert.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("Bearer token-%d", i), req.Header.Get("Authorization"))
+	}
+	assert.Equal(t, []string{"client_credentials", "refresh_token"}, grantTypes)
+}
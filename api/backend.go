@@ -0,0 +1,56 @@
+package api
+
+import "context"
+
+// Backend is operations on permissions and default reviewers of repositories,
+// implemented for each Bitbucket product.
+//
+// For Bitbucket Data Center, workspace is the project key and repository is the repository slug.
+type Backend interface {
+	GetCurrentUser(ctx context.Context) (CurrentUser, error)
+
+	ListPermission(ctx context.Context, workspace, repository string) ([]Permission, error)
+	UpdatePermissions(ctx context.Context, workspace, repository string, operations []Operation) error
+
+	ListDefaultReviewers(ctx context.Context, workspace, repository string) ([]Account, error)
+	AddDefaultReviewers(ctx context.Context, workspace, repository string, reviewers []string) ([]Account, error)
+	DeleteDefaultReviewers(ctx context.Context, workspace, repository string, reviewers []string) ([]Account, error)
+}
+
+// BranchRestrictionBackend is operations on branch restrictions of repositories.
+// Only Bitbucket Cloud implements it.
+type BranchRestrictionBackend interface {
+	ListBranchRestrictions(ctx context.Context, workspace, repository string) ([]BranchRestriction, error)
+	UpdateBranchRestrictions(ctx context.Context, workspace, repository string, operations []BranchRestrictionOperation) error
+}
+
+// BranchingModelBackend is operations on branching model settings of repositories.
+// Only Bitbucket Cloud implements it.
+type BranchingModelBackend interface {
+	GetBranchingModel(ctx context.Context, workspace, repository string) (BranchingModel, error)
+	UpdateBranchingModel(ctx context.Context, workspace, repository string, changes []SettingChange) error
+}
+
+// RepositorySettingsBackend is operations on properties of repositories.
+// Only Bitbucket Cloud implements it.
+type RepositorySettingsBackend interface {
+	GetRepositorySettings(ctx context.Context, workspace, repository string) (RepositorySettings, error)
+	UpdateRepositorySettings(ctx context.Context, workspace, repository string, changes []SettingChange) error
+}
+
+// WebhookBackend is operations on webhooks of repositories.
+// Only Bitbucket Cloud implements it.
+type WebhookBackend interface {
+	ListWebhooks(ctx context.Context, workspace, repository string) ([]Webhook, error)
+	UpdateWebhooks(ctx context.Context, workspace, repository string, operations []WebhookOperation) error
+}
+
+var (
+	_ Backend = (*BitbucketApi)(nil)
+	_ Backend = (*DataCenterApi)(nil)
+
+	_ BranchRestrictionBackend  = (*BitbucketApi)(nil)
+	_ BranchingModelBackend     = (*BitbucketApi)(nil)
+	_ RepositorySettingsBackend = (*BitbucketApi)(nil)
+	_ WebhookBackend            = (*BitbucketApi)(nil)
+)
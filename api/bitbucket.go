@@ -6,10 +6,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"path"
+	"regexp"
+	"strconv"
 	"strings"
-	"sync"
+	"time"
 )
 
 const urlBitbucketApi = "https://api.bitbucket.org/2.0"
@@ -21,26 +25,43 @@ const (
 	endpointPermissionConfigGroup  = "/repositories/%s/%s/permissions-config/groups/%s"
 	endpointDefaultReviewers       = "/repositories/%s/%s/default-reviewers"
 	endpointDefaultReviewer        = "/repositories/%s/%s/default-reviewers/%s"
+	endpointUser                   = "/user"
 )
 
 type BitbucketApi struct {
-	hc *http.Client
+	hc          *http.Client
+	logger      *slog.Logger
+	pageOptions PageOptions
 
-	baseUrl  string
-	username string
-	password string
+	baseUrl string
+	auth    Authenticator
+	cache   *Cache
 }
 
+// NewBitbucketApi creates a client authenticating with username and app password.
 func NewBitbucketApi(
 	hc *http.Client,
 	username string,
 	password string,
+	opts ...Option,
 ) *BitbucketApi {
+	return NewBitbucketApiWithAuth(hc, BasicAuth{Username: username, Password: password}, opts...)
+}
+
+// NewBitbucketApiWithAuth creates a client authenticating with the authenticator.
+func NewBitbucketApiWithAuth(
+	hc *http.Client,
+	auth Authenticator,
+	opts ...Option,
+) *BitbucketApi {
+	o := newOptions(urlBitbucketApi, opts)
 	return &BitbucketApi{
-		hc:       hc,
-		baseUrl:  urlBitbucketApi,
-		username: username,
-		password: password,
+		hc:          hc,
+		logger:      o.logger,
+		pageOptions: PageOptions{Pagelen: o.pagelen, Parallel: o.parallel},
+		baseUrl:     strings.TrimSuffix(o.baseUrl, "/"),
+		auth:        auth,
+		cache:       o.cache,
 	}
 }
 
@@ -59,6 +80,29 @@ const (
 	PermissionTypeAdmin PermissionType = "admin"
 )
 
+func ParsePermissionType(s string) (PermissionType, error) {
+	p := PermissionType(strings.ToLower(s))
+	if p.Level() == 0 {
+		return "", fmt.Errorf("invalid permission %q: must be one of read, write, admin", s)
+	}
+	return p, nil
+}
+
+// Level returns the rank of the permission. Higher level grants more access.
+// Unknown or empty permission is 0.
+func (p PermissionType) Level() int {
+	switch p {
+	case PermissionTypeRead:
+		return 1
+	case PermissionTypeWrite:
+		return 2
+	case PermissionTypeAdmin:
+		return 3
+	default:
+		return 0
+	}
+}
+
 type Permission struct {
 	ObjectId       string
 	ObjectName     string
@@ -66,12 +110,38 @@ type Permission struct {
 	PermissionType PermissionType
 }
 
+// Match reports whether the object id or name of the permission matches the shell pattern.
+func (p Permission) Match(pattern string) bool {
+	if ok, _ := path.Match(pattern, p.ObjectId); ok {
+		return true
+	}
+	ok, _ := path.Match(pattern, p.ObjectName)
+	return ok
+}
+
 type Account struct {
 	Uuid        string `json:"uuid"`
 	Nickname    string `json:"nickname"`
 	DisplayName string `json:"display_name"`
 }
 
+// CurrentUser is the authenticated account and scopes granted to the credentials.
+// Scopes is nil if Bitbucket does not report them.
+type CurrentUser struct {
+	Account
+	Scopes []string
+}
+
+// HasScope reports whether the scope is granted.
+func (u CurrentUser) HasScope(scope string) bool {
+	for _, v := range u.Scopes {
+		if v == scope {
+			return true
+		}
+	}
+	return false
+}
+
 // Response from Bitbucket API
 
 type errorResponse struct {
@@ -119,39 +189,185 @@ type bitbucketGroup struct {
 }
 
 func (ba BitbucketApi) do(ctx context.Context, endpoint, method string, body io.Reader) ([]byte, error) {
-	u, err := url.Parse(ba.baseUrl + endpoint)
+	b, _, err := ba.doWithHeader(ctx, endpoint, method, body)
+	return b, err
+}
+
+// doWithHeader is the same as do but also returns headers of the response.
+// GET requests are served from the cache if configured, and other requests invalidate cached entries of the repository.
+func (ba BitbucketApi) doWithHeader(ctx context.Context, endpoint, method string, body io.Reader) ([]byte, http.Header, error) {
+	if ba.cache == nil {
+		return request(ctx, ba.hc, ba.logger, ba.auth, ba.baseUrl+endpoint, method, body)
+	}
+	if method == "GET" {
+		return ba.cachedGet(ctx, endpoint)
+	}
+
+	b, h, err := request(ctx, ba.hc, ba.logger, ba.auth, ba.baseUrl+endpoint, method, body)
+	// invalidate even if failed since the repository may be changed
+	if err := ba.cache.invalidate(accountOf(ba.auth), ba.baseUrl+repositoryPrefix(endpoint)); err != nil {
+		ba.logger.WarnContext(ctx, "failed to invalidate cache", slog.Any("error", err))
+	}
+	return b, h, err
+}
+
+// request sends an authenticated request and returns the body and headers of the response.
+// Responses with status 400 or above are returned as error.
+// Requests are not logged if logger is nil.
+func request(ctx context.Context, hc *http.Client, logger *slog.Logger, auth Authenticator, rawUrl, method string, body io.Reader) ([]byte, http.Header, error) {
+	_, b, h, err := requestWithHeader(ctx, hc, logger, auth, rawUrl, method, body, nil)
+	return b, h, err
+}
+
+// maxRetries is the number of times a request is retried while Bitbucket is rate limiting or temporarily unavailable.
+const maxRetries = 3
+
+// maxRetryAfter caps the wait requested by Retry-After, so that a command doesn't hang on a large value.
+const maxRetryAfter = 30 * time.Second
+
+// retryWait is the wait before the first retry, which doubles on each retry. Retry-After of the response takes precedence.
+var retryWait = time.Second
+
+type attemptKey struct{}
+
+// attemptOf returns the number of the attempt of the request, which is 0 for the first try and counts up on retries.
+func attemptOf(ctx context.Context) int {
+	n, _ := ctx.Value(attemptKey{}).(int)
+	return n
+}
+
+// requestWithHeader is the same as request but sends additional headers and also returns the status code.
+// Requests are retried up to maxRetries times when retryable.
+func requestWithHeader(ctx context.Context, hc *http.Client, logger *slog.Logger, auth Authenticator, rawUrl, method string, body io.Reader, header http.Header) (int, []byte, http.Header, error) {
+	u, err := url.Parse(rawUrl)
 	if err != nil {
-		return nil, err
+		return 0, nil, nil, err
 	}
 
-	fmt.Printf("request to %s\n", u.String())
+	if logger == nil {
+		logger = discardLogger
+	}
+
+	// the body is sent again on retries
+	var payload []byte
+	if body != nil {
+		payload, err = io.ReadAll(body)
+		if err != nil {
+			return 0, nil, nil, err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		status, b, h, err := requestOnce(context.WithValue(ctx, attemptKey{}, attempt), hc, logger, auth, u, method, payload, header)
+		if attempt >= maxRetries || !retryable(method, status) {
+			return status, b, h, err
+		}
+
+		wait := retryAfter(h, retryWait<<attempt)
+		logger.InfoContext(ctx, "retry",
+			slog.String("method", method),
+			slog.String("url", redactUrl(u)),
+			slog.Int("status", status),
+			slog.Int("retry", attempt+1),
+			slog.Duration("wait", wait),
+		)
+		select {
+		case <-ctx.Done():
+			return status, b, h, err
+		case <-time.After(wait):
+		}
+	}
+}
+
+// retryable reports whether the request may succeed if it is sent again without changing anything twice.
+// 429 is retried for any method, since Bitbucket rejects rate limited requests before applying them.
+// 502, 503 and 504 are retried only for GET: a write may have been applied before the gateway failed,
+// and sending it again would hide which operations were applied. Network errors are not retried.
+func retryable(method string, status int) bool {
+	switch status {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return method == "GET"
+	}
+	return false
+}
+
+// retryAfter returns the wait of the Retry-After header in seconds capped by maxRetryAfter, or def if it has none.
+func retryAfter(h http.Header, def time.Duration) time.Duration {
+	s, err := strconv.Atoi(h.Get("Retry-After"))
+	if err != nil || s < 0 {
+		return def
+	}
+	return min(time.Duration(s)*time.Second, maxRetryAfter)
+}
 
+// requestOnce sends the request without retrying. The status is 0 if no response was received.
+func requestOnce(ctx context.Context, hc *http.Client, logger *slog.Logger, auth Authenticator, u *url.URL, method string, payload []byte, header http.Header) (int, []byte, http.Header, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
 	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
 	if err != nil {
-		return nil, err
+		return 0, nil, nil, err
 	}
 
-	req.SetBasicAuth(ba.username, ba.password)
+	err = auth.Authenticate(ctx, req)
+	if err != nil {
+		return 0, nil, nil, err
+	}
 	if body != nil {
 		req.Header.Add("Content-Type", "application/json")
 	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
 
-	res, err := ba.hc.Do(req)
+	logger.DebugContext(ctx, "request",
+		slog.String("method", method),
+		slog.String("url", redactUrl(u)),
+		slog.Int("retry", attemptOf(ctx)),
+		slog.Any("header", redactHeader(req.Header)),
+	)
+
+	start := time.Now()
+	res, err := hc.Do(req)
 	if err != nil {
-		return nil, err
+		logger.WarnContext(ctx, "request failed",
+			slog.String("method", method),
+			slog.String("url", redactUrl(u)),
+			slog.Int("retry", attemptOf(ctx)),
+			slog.Duration("duration", time.Since(start)),
+			slog.Any("error", err),
+		)
+		return 0, nil, nil, err
 	}
 
 	defer res.Body.Close()
 
 	b, err := io.ReadAll(res.Body)
 	if err != nil {
-		return nil, err
+		return 0, nil, nil, err
+	}
+
+	level := slog.LevelInfo
+	if res.StatusCode >= 400 {
+		level = slog.LevelWarn
 	}
+	logger.Log(ctx, level, "response",
+		slog.String("method", method),
+		slog.String("url", redactUrl(u)),
+		slog.Int("status", res.StatusCode),
+		slog.Int("retry", attemptOf(ctx)),
+		slog.Duration("duration", time.Since(start)),
+	)
+	logger.DebugContext(ctx, "response header", slog.Any("header", redactHeader(res.Header)))
 
 	if res.StatusCode >= 400 {
 		var e errorResponse
 		err = json.Unmarshal(b, &e)
-		if err != nil {
+		if err != nil || e.Error.Message == "" {
 			e = errorResponse{
 				Error: errorField{
 					Message: string(b),
@@ -159,84 +375,34 @@ func (ba BitbucketApi) do(ctx context.Context, endpoint, method string, body io.
 			}
 		}
 
-		return nil, fmt.Errorf("http request error: %v, %v", res.Status, e)
+		return res.StatusCode, nil, res.Header, fmt.Errorf("http request error: %v, %v", res.Status, e)
 	}
 
-	return b, nil
+	return res.StatusCode, b, res.Header, nil
 }
 
 // ListGroupPermission gets group permissions for a repository.
 func (ba *BitbucketApi) ListGroupPermission(ctx context.Context, workspace, repository string) ([]Permission, error) {
-	permissions := make([]Permission, 0)
-	next := fmt.Sprintf(endpointPermissionConfigGroups, workspace, repository)
-	for next != "" {
-		res, err := ba.do(ctx, next, "GET", nil)
-		if err != nil {
-			return nil, err
+	return listAll(ctx, ba, fmt.Sprintf(endpointPermissionConfigGroups, workspace, repository), ba.pageOptions, func(v repositoryPermissionGroup) Permission {
+		return Permission{
+			ObjectId:       v.Group.Slug,
+			ObjectName:     v.Group.Name,
+			ObjectType:     ObjectType(v.Group.Type),
+			PermissionType: PermissionType(v.Permission),
 		}
-		var groupPermission response[repositoryPermissionGroup]
-		err = json.Unmarshal(res, &groupPermission)
-		if err != nil {
-			return nil, err
-		}
-
-		for _, v := range groupPermission.Values {
-			p := Permission{
-				ObjectId:       v.Group.Slug,
-				ObjectName:     v.Group.Name,
-				ObjectType:     ObjectType(v.Group.Type),
-				PermissionType: PermissionType(v.Permission),
-			}
-			permissions = append(permissions, p)
-		}
-
-		if groupPermission.Next != nil {
-			next = *groupPermission.Next
-			next = strings.TrimPrefix(next, urlBitbucketApi)
-		} else {
-			next = ""
-		}
-	}
-
-	return permissions, nil
+	})
 }
 
-// ListUserPermission gets group permissions for a repository.
+// ListUserPermission gets user permissions for a repository.
 func (ba *BitbucketApi) ListUserPermission(ctx context.Context, workspace, repository string) ([]Permission, error) {
-	permissions := make([]Permission, 0)
-
-	next := fmt.Sprintf(endpointPermissionConfigUsers, workspace, repository)
-
-	for next != "" {
-		res, err := ba.do(ctx, next, "GET", nil)
-		if err != nil {
-			return nil, err
-		}
-		var userPermission response[repositoryPermissionUser]
-		err = json.Unmarshal(res, &userPermission)
-		if err != nil {
-			return nil, err
+	return listAll(ctx, ba, fmt.Sprintf(endpointPermissionConfigUsers, workspace, repository), ba.pageOptions, func(v repositoryPermissionUser) Permission {
+		return Permission{
+			ObjectId:       v.User.Uuid,
+			ObjectName:     v.User.Nickname,
+			ObjectType:     ObjectType(v.User.Type),
+			PermissionType: PermissionType(v.Permission),
 		}
-
-		for _, v := range userPermission.Values {
-			p := Permission{
-				ObjectId:       v.User.Uuid,
-				ObjectName:     v.User.Nickname,
-				ObjectType:     ObjectType(v.User.Type),
-				PermissionType: PermissionType(v.Permission),
-			}
-			permissions = append(permissions, p)
-		}
-
-		if userPermission.Next != nil {
-			next = *userPermission.Next
-			next = strings.TrimPrefix(next, urlBitbucketApi)
-		} else {
-			next = ""
-		}
-	}
-
-	return permissions, nil
+	})
 }
 
 // ListPermission gets permissions for a repository.
@@ -259,17 +425,23 @@ func (ba *BitbucketApi) ListPermission(ctx context.Context, workspace, repositor
 }
 
 // UpdatePermissions updates permissions of a repository according to operations.
+// It stops at the first failure or cancellation of ctx with *UpdateError[Operation] reporting applied and skipped operations.
+// Users to add must be specified by UUID or account id, since the API doesn't resolve nicknames.
 func (ba *BitbucketApi) UpdatePermissions(ctx context.Context, workspace, repository string, operations []Operation) error {
 	for _, v := range operations {
-		endpoint := ""
+		if v.add && v.objectType == ObjectTypeUser && !isUserId(v.objectId) {
+			return fmt.Errorf("user %s must be specified by UUID or account id to add", v.objectId)
+		}
+	}
+
+	return applyOperations(ctx, operations, func(ctx context.Context, v Operation) error {
+		endpoint := fmt.Sprintf(endpointPermissionConfigUser, workspace, repository, v.objectId)
+		if v.objectType == ObjectTypeGroup {
+			endpoint = fmt.Sprintf(endpointPermissionConfigGroup, workspace, repository, v.objectId)
+		}
+
 		switch {
 		case v.update, v.add:
-			if v.objectType == ObjectTypeUser {
-				endpoint = fmt.Sprintf(endpointPermissionConfigUser, workspace, repository, v.objectId)
-			} else {
-				endpoint = fmt.Sprintf(endpointPermissionConfigGroup, workspace, repository, v.objectId)
-			}
-
 			body, err := json.Marshal(map[string]string{
 				"permission": string(v.permissionAfter),
 			})
@@ -277,101 +449,113 @@ func (ba *BitbucketApi) UpdatePermissions(ctx context.Context, workspace, reposi
 				return err
 			}
 			_, err = ba.do(ctx, endpoint, "PUT", bytes.NewBuffer(body))
-			if err != nil {
-				return err
-			}
+			return err
 
 		case v.remove:
-			if v.objectType == ObjectTypeUser {
-				endpoint = fmt.Sprintf(endpointPermissionConfigUser, workspace, repository, v.objectId)
-			} else {
-				endpoint = fmt.Sprintf(endpointPermissionConfigGroup, workspace, repository, v.objectId)
-			}
 			_, err := ba.do(ctx, endpoint, "DELETE", nil)
-			if err != nil {
-				return err
-			}
-
+			return err
 		}
-	}
-	return nil
+		return nil
+	})
 }
 
-// ListDefaultReviewers gets default reviewers for a repository.
-func (ba *BitbucketApi) ListDefaultReviewers(ctx context.Context, workspace, repository string) ([]Account, error) {
-	accounts := make([]Account, 0)
+var accountIdPattern = regexp.MustCompile(`^([0-9]+:[0-9a-fA-F-]+|[0-9a-fA-F]{24})$`)
 
-	next := fmt.Sprintf(endpointDefaultReviewers, workspace, repository)
+// isUserId reports whether s is a UUID in braces or an account id of a user.
+func isUserId(s string) bool {
+	return strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") || accountIdPattern.MatchString(s)
+}
 
-	for next != "" {
-		res, err := ba.do(ctx, next, "GET", nil)
-		if err != nil {
-			return nil, err
-		}
-		var user response[bitbucketUser]
-		err = json.Unmarshal(res, &user)
-		if err != nil {
-			return nil, err
+// ListDefaultReviewers gets default reviewers for a repository.
+func (ba *BitbucketApi) ListDefaultReviewers(ctx context.Context, workspace, repository string) ([]Account, error) {
+	return listAll(ctx, ba, fmt.Sprintf(endpointDefaultReviewers, workspace, repository), ba.pageOptions, func(v bitbucketUser) Account {
+		return Account{
+			Uuid:        v.Uuid,
+			Nickname:    v.Nickname,
+			DisplayName: v.DisplayName,
 		}
+	})
+}
 
-		for _, v := range user.Values {
-			a := Account{
-				Uuid:        v.Uuid,
-				Nickname:    v.Nickname,
-				DisplayName: v.DisplayName,
-			}
-			accounts = append(accounts, a)
-		}
+// DefaultReviewerOperation is an operation to add or delete a default reviewer.
+type DefaultReviewerOperation struct {
+	reviewer string
+	remove   bool
+}
 
-		if user.Next != nil {
-			next = *user.Next
-			next = strings.TrimPrefix(next, urlBitbucketApi)
-		} else {
-			next = ""
-		}
-	}
+func (o DefaultReviewerOperation) Same() bool {
+	return false
+}
 
-	return accounts, nil
+func (o DefaultReviewerOperation) Message() string {
+	if o.remove {
+		return fmt.Sprintf("Remove: reviewer %s", o.reviewer)
+	}
+	return fmt.Sprintf("Add: reviewer %s", o.reviewer)
 }
 
 // DeleteDefaultReviewers deletes default reviewers for a repository.
+// It stops at the first failure or cancellation of ctx with *UpdateError[DefaultReviewerOperation].
 // - reviewers: list of the username or the UUID
 func (ba *BitbucketApi) DeleteDefaultReviewers(ctx context.Context, workspace, repository string, reviewers []string) ([]Account, error) {
-	accounts := make([]Account, 0)
+	return ba.updateDefaultReviewers(ctx, workspace, repository, reviewers, true)
+}
 
-	var wg sync.WaitGroup
-	for _, cv := range reviewers {
-		wg.Add(1)
-		go func(reviewer string) {
-			defer wg.Done()
+// AddDefaultReviewers adds default reviewers for a repository.
+// It stops at the first failure or cancellation of ctx with *UpdateError[DefaultReviewerOperation].
+// - reviewers: list of the username or the UUID
+func (ba *BitbucketApi) AddDefaultReviewers(ctx context.Context, workspace, repository string, reviewers []string) ([]Account, error) {
+	return ba.updateDefaultReviewers(ctx, workspace, repository, reviewers, false)
+}
 
-			u := fmt.Sprintf(endpointDefaultReviewer, workspace, repository, reviewer)
-			ba.do(ctx, u, "DELETE", nil)
-		}(cv)
+func (ba *BitbucketApi) updateDefaultReviewers(ctx context.Context, workspace, repository string, reviewers []string, remove bool) ([]Account, error) {
+	operations := make([]DefaultReviewerOperation, 0, len(reviewers))
+	for _, v := range reviewers {
+		operations = append(operations, DefaultReviewerOperation{reviewer: v, remove: remove})
 	}
 
-	wg.Wait()
+	err := applyOperations(ctx, operations, func(ctx context.Context, v DefaultReviewerOperation) error {
+		method := "PUT"
+		if v.remove {
+			method = "DELETE"
+		}
+		_, err := ba.do(ctx, fmt.Sprintf(endpointDefaultReviewer, workspace, repository, v.reviewer), method, nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	return accounts, nil
+	return make([]Account, 0), nil
 }
 
-// AddDefaultReviewers adds default reviewers for a repository.
-// - reviewers: list of the username or the UUID
-func (ba *BitbucketApi) AddDefaultReviewers(ctx context.Context, workspace, repository string, reviewers []string) ([]Account, error) {
-	accounts := make([]Account, 0)
-
-	var wg sync.WaitGroup
-	for _, cv := range reviewers {
-		wg.Add(1)
-		go func(reviewer string) {
-			defer wg.Done()
+// GetCurrentUser gets the authenticated account and its scopes.
+// The cache is bypassed, so that the account and scopes of the current credentials are reported after they changed.
+func (ba *BitbucketApi) GetCurrentUser(ctx context.Context) (CurrentUser, error) {
+	res, header, err := request(ctx, ba.hc, ba.logger, ba.auth, ba.baseUrl+endpointUser, "GET", nil)
+	if err != nil {
+		return CurrentUser{}, err
+	}
 
-			u := fmt.Sprintf(endpointDefaultReviewer, workspace, repository, reviewer)
-			ba.do(ctx, u, "PUT", nil)
-		}(cv)
+	var user bitbucketUser
+	err = json.Unmarshal(res, &user)
+	if err != nil {
+		return CurrentUser{}, err
 	}
 
-	wg.Wait()
+	cu := CurrentUser{
+		Account: Account{
+			Uuid:        user.Uuid,
+			Nickname:    user.Nickname,
+			DisplayName: user.DisplayName,
+		},
+	}
+	if scopes := header.Get("X-OAuth-Scopes"); scopes != "" {
+		cu.Scopes = make([]string, 0)
+		for _, v := range strings.Split(scopes, ",") {
+			cu.Scopes = append(cu.Scopes, strings.TrimSpace(v))
+		}
+	}
 
-	return accounts, nil
+	return cu, nil
 }
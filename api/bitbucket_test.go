@@ -6,11 +6,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -44,10 +46,9 @@ func TestBitbucketApi_ListPermissions(t *testing.T) {
 	defer ts.Close()
 
 	ba := &BitbucketApi{
-		hc:       http.DefaultClient,
-		baseUrl:  ts.URL,
-		username: "user",
-		password: "pass",
+		hc:      http.DefaultClient,
+		baseUrl: ts.URL,
+		auth:    BasicAuth{Username: "user", Password: "pass"},
 	}
 	ctx := context.Background()
 	got, err := ba.ListPermission(ctx, "myworkspace", "myrepository")
@@ -227,10 +228,9 @@ func TestBitbucketApi_UpdatePermissions(t *testing.T) {
 			defer ts.Close()
 
 			ba := &BitbucketApi{
-				hc:       http.DefaultClient,
-				baseUrl:  ts.URL,
-				username: "user",
-				password: "pass",
+				hc:      http.DefaultClient,
+				baseUrl: ts.URL,
+				auth:    BasicAuth{Username: "user", Password: "pass"},
 			}
 			ctx := context.Background()
 			err := ba.UpdatePermissions(ctx, tt.args.workspace, tt.args.repository, tt.args.operations)
@@ -238,3 +238,167 @@ func TestBitbucketApi_UpdatePermissions(t *testing.T) {
 		})
 	}
 }
+
+func TestBitbucketApi_UpdatePermissions_nickname(t *testing.T) {
+	requested := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+	}))
+	defer ts.Close()
+
+	ba := &BitbucketApi{
+		hc:      http.DefaultClient,
+		baseUrl: ts.URL,
+		auth:    BasicAuth{Username: "user", Password: "pass"},
+	}
+	operations := []Operation{
+		NewAddOperation(Permission{ObjectId: "557058:f0b5c5d4-1234-5678-9abc-def012345678", ObjectName: "user1", ObjectType: ObjectTypeUser, PermissionType: PermissionTypeRead}),
+		NewAddOperation(Permission{ObjectId: "john-doe", ObjectName: "john-doe", ObjectType: ObjectTypeUser, PermissionType: PermissionTypeRead}),
+	}
+	err := ba.UpdatePermissions(context.Background(), "myworkspace", "myrepository", operations)
+	assert.EqualError(t, err, "user john-doe must be specified by UUID or account id to add")
+	assert.False(t, requested)
+}
+
+func TestBitbucketApi_AddDefaultReviewers(t *testing.T) {
+	requests := make([]string, 0)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		if strings.HasSuffix(r.URL.Path, "/{2222}") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{}`)
+	}))
+	defer ts.Close()
+
+	ba := &BitbucketApi{
+		hc:      http.DefaultClient,
+		baseUrl: ts.URL,
+		auth:    BasicAuth{Username: "user", Password: "pass"},
+	}
+	_, err := ba.AddDefaultReviewers(context.Background(), "myworkspace", "myrepository", []string{"{1111}", "{2222}", "{3333}"})
+
+	var updateErr *UpdateError[DefaultReviewerOperation]
+	assert.ErrorAs(t, err, &updateErr)
+	assert.Equal(t, []DefaultReviewerOperation{{reviewer: "{1111}"}}, updateErr.Applied)
+	assert.Equal(t, "Add: reviewer {2222}", updateErr.Failed.Message())
+	assert.Equal(t, []DefaultReviewerOperation{{reviewer: "{3333}"}}, updateErr.Skipped)
+	assert.Equal(t, []string{
+		"PUT /repositories/myworkspace/myrepository/default-reviewers/{1111}",
+		"PUT /repositories/myworkspace/myrepository/default-reviewers/{2222}",
+	}, requests)
+
+	requests = requests[:0]
+	_, err = ba.DeleteDefaultReviewers(context.Background(), "myworkspace", "myrepository", []string{"{1111}"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"DELETE /repositories/myworkspace/myrepository/default-reviewers/{1111}"}, requests)
+}
+
+func TestBitbucketApi_GetCurrentUser(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/user", r.URL.Path)
+		w.Header().Set("X-OAuth-Scopes", "repository:admin, pullrequest")
+		fmt.Fprint(w, `{"type":"user","uuid":"{1234-fddd-5678-a111}","nickname":"john-doe","display_name":"John Doe"}`)
+	}))
+	defer ts.Close()
+
+	ba := &BitbucketApi{
+		hc:      http.DefaultClient,
+		baseUrl: ts.URL,
+		auth:    BasicAuth{Username: "user", Password: "pass"},
+	}
+	got, err := ba.GetCurrentUser(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, CurrentUser{
+		Account: Account{Uuid: "{1234-fddd-5678-a111}", Nickname: "john-doe", DisplayName: "John Doe"},
+		Scopes:  []string{"repository:admin", "pullrequest"},
+	}, got)
+	assert.True(t, got.HasScope("repository:admin"))
+}
+
+func TestBitbucketApi_logging(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"type":"user","uuid":"{1234-fddd-5678-a111}"}`)
+	}))
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	ba := NewBitbucketApiWithAuth(http.DefaultClient, BearerToken{Token: "secret-token"}, WithBaseUrl(ts.URL), WithLogger(logger))
+	_, err := ba.do(context.Background(), "/user?access_token=secret-query", "GET", nil)
+	assert.NoError(t, err)
+
+	logs := buf.String()
+	assert.NotContains(t, logs, "secret-token")
+	assert.NotContains(t, logs, "secret-query")
+
+	var response map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(logs), "\n") {
+		var v map[string]any
+		assert.NoError(t, json.Unmarshal([]byte(line), &v))
+		if v["msg"] == "response" {
+			response = v
+		}
+	}
+	assert.Equal(t, "GET", response["method"])
+	assert.Equal(t, ts.URL+"/user?access_token=REDACTED", response["url"])
+	assert.Equal(t, float64(200), response["status"])
+	assert.Equal(t, float64(0), response["retry"])
+	assert.Contains(t, response, "duration")
+}
+
+func TestBitbucketApi_retry(t *testing.T) {
+	defer func(d time.Duration) { retryWait = d }(retryWait)
+	retryWait = time.Millisecond
+
+	requests := make([]string, 0)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		requests = append(requests, fmt.Sprintf("%s %s %s", r.Method, r.URL.Path, b))
+		switch {
+		case r.URL.Path == "/limited" && len(requests) < 3:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case r.URL.Path == "/unavailable":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			fmt.Fprint(w, `{}`)
+		}
+	}))
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	ba := NewBitbucketApi(http.DefaultClient, "user", "pass", WithBaseUrl(ts.URL), WithLogger(logger))
+	ctx := context.Background()
+
+	_, err := ba.do(ctx, "/limited", "PUT", strings.NewReader(`{"permission":"write"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		`PUT /limited {"permission":"write"}`,
+		`PUT /limited {"permission":"write"}`,
+		`PUT /limited {"permission":"write"}`,
+	}, requests)
+	assert.Equal(t, 2, strings.Count(buf.String(), `"msg":"retry"`))
+
+	requests = requests[:0]
+	_, err = ba.do(ctx, "/unavailable", "GET", nil)
+	assert.Error(t, err)
+	assert.Len(t, requests, maxRetries+1)
+
+	// writes may have been applied by unavailable Bitbucket
+	for _, method := range []string{"PUT", "DELETE", "POST"} {
+		requests = requests[:0]
+		_, err = ba.do(ctx, "/unavailable", method, nil)
+		assert.Error(t, err)
+		assert.Len(t, requests, 1, method)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	assert.Equal(t, 5*time.Second, retryAfter(http.Header{"Retry-After": {"5"}}, time.Second))
+	assert.Equal(t, maxRetryAfter, retryAfter(http.Header{"Retry-After": {"3600"}}, time.Second))
+	assert.Equal(t, time.Second, retryAfter(http.Header{"Retry-After": {"soon"}}, time.Second))
+	assert.Equal(t, time.Second, retryAfter(http.Header{}, time.Second))
+}
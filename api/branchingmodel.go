@@ -0,0 +1,205 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const endpointBranchingModelSettings = "/repositories/%s/%s/branching-model/settings"
+
+// BranchingModel is the branching model settings of a repository.
+type BranchingModel struct {
+	Development BranchingModelBranch `json:"development"`
+	Production  BranchingModelBranch `json:"production"`
+	BranchTypes []BranchType         `json:"branch_types"`
+}
+
+// BranchingModelBranch is the development or production branch.
+// Name is ignored if UseMainBranch. Enabled is only for the production branch.
+type BranchingModelBranch struct {
+	Name          string `json:"name"`
+	UseMainBranch bool   `json:"use_mainbranch"`
+	Enabled       bool   `json:"enabled,omitempty"`
+}
+
+// BranchType is the prefix of branches of the kind such as feature, bugfix, release and hotfix.
+type BranchType struct {
+	Kind    string `json:"kind"`
+	Enabled bool   `json:"enabled"`
+	Prefix  string `json:"prefix,omitempty"`
+}
+
+// Settings returns the fields of the branching model compared by MakeSettingChanges.
+// Branch names used instead of the main branch and prefixes of disabled branch types are left out, since they are not used.
+func (m BranchingModel) Settings() []Setting {
+	settings := []Setting{
+		{Field: "development.use_mainbranch", Value: formatBool(m.Development.UseMainBranch)},
+	}
+	if !m.Development.UseMainBranch {
+		settings = append(settings, Setting{Field: "development.branch", Value: m.Development.Name})
+	}
+	settings = append(settings,
+		Setting{Field: "production.enabled", Value: formatBool(m.Production.Enabled)},
+		Setting{Field: "production.use_mainbranch", Value: formatBool(m.Production.UseMainBranch)},
+	)
+	if !m.Production.UseMainBranch {
+		settings = append(settings, Setting{Field: "production.branch", Value: m.Production.Name})
+	}
+	for _, v := range m.BranchTypes {
+		settings = append(settings, Setting{Field: fmt.Sprintf("branch_types.%s.enabled", v.Kind), Value: formatBool(v.Enabled)})
+		if v.Enabled {
+			settings = append(settings, Setting{Field: fmt.Sprintf("branch_types.%s.prefix", v.Kind), Value: v.Prefix})
+		}
+	}
+	return settings
+}
+
+type bitbucketBranchingModelBranch struct {
+	Name          *string `json:"name,omitempty"`
+	UseMainBranch *bool   `json:"use_mainbranch,omitempty"`
+	Enabled       *bool   `json:"enabled,omitempty"`
+}
+
+type bitbucketBranchType struct {
+	Kind    string  `json:"kind"`
+	Enabled *bool   `json:"enabled,omitempty"`
+	Prefix  *string `json:"prefix,omitempty"`
+}
+
+// bitbucketBranchingModel is the body of the branching model settings.
+// Fields are pointers since only fields in the body are changed by PUT.
+type bitbucketBranchingModel struct {
+	Development *bitbucketBranchingModelBranch `json:"development,omitempty"`
+	Production  *bitbucketBranchingModelBranch `json:"production,omitempty"`
+	BranchTypes []bitbucketBranchType          `json:"branch_types,omitempty"`
+}
+
+func (v *bitbucketBranchingModelBranch) branch() BranchingModelBranch {
+	var b BranchingModelBranch
+	if v == nil {
+		return b
+	}
+	if v.Name != nil {
+		b.Name = *v.Name
+	}
+	if v.UseMainBranch != nil {
+		b.UseMainBranch = *v.UseMainBranch
+	}
+	if v.Enabled != nil {
+		b.Enabled = *v.Enabled
+	}
+	return b
+}
+
+// GetBranchingModel gets the branching model settings of a repository.
+func (ba *BitbucketApi) GetBranchingModel(ctx context.Context, workspace, repository string) (BranchingModel, error) {
+	res, err := ba.do(ctx, fmt.Sprintf(endpointBranchingModelSettings, workspace, repository), "GET", nil)
+	if err != nil {
+		return BranchingModel{}, err
+	}
+
+	var v bitbucketBranchingModel
+	if err := json.Unmarshal(res, &v); err != nil {
+		return BranchingModel{}, err
+	}
+
+	m := BranchingModel{
+		Development: v.Development.branch(),
+		Production:  v.Production.branch(),
+		BranchTypes: make([]BranchType, 0, len(v.BranchTypes)),
+	}
+	for _, t := range v.BranchTypes {
+		bt := BranchType{Kind: t.Kind}
+		if t.Enabled != nil {
+			bt.Enabled = *t.Enabled
+		}
+		if t.Prefix != nil {
+			bt.Prefix = *t.Prefix
+		}
+		m.BranchTypes = append(m.BranchTypes, bt)
+	}
+	return m, nil
+}
+
+// UpdateBranchingModel changes fields of the branching model settings of a repository.
+// Fields not in changes are kept.
+func (ba *BitbucketApi) UpdateBranchingModel(ctx context.Context, workspace, repository string, changes []SettingChange) error {
+	body, err := branchingModelBody(changes)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	_, err = ba.do(ctx, fmt.Sprintf(endpointBranchingModelSettings, workspace, repository), "PUT", bytes.NewBuffer(b))
+	return err
+}
+
+func branchingModelBody(changes []SettingChange) (bitbucketBranchingModel, error) {
+	var body bitbucketBranchingModel
+	branchTypes := map[string]int{}
+
+	for _, c := range changes {
+		value := c.After
+		parts := strings.Split(c.Field, ".")
+
+		switch {
+		case len(parts) == 2 && (parts[0] == "development" || parts[0] == "production"):
+			branch := &body.Development
+			if parts[0] == "production" {
+				branch = &body.Production
+			}
+			if *branch == nil {
+				*branch = &bitbucketBranchingModelBranch{}
+			}
+
+			switch {
+			case parts[1] == "branch":
+				(*branch).Name = &value
+			case parts[1] == "use_mainbranch":
+				b, err := parseBool(c.Field, value)
+				if err != nil {
+					return body, err
+				}
+				(*branch).UseMainBranch = &b
+			case parts[1] == "enabled" && parts[0] == "production":
+				b, err := parseBool(c.Field, value)
+				if err != nil {
+					return body, err
+				}
+				(*branch).Enabled = &b
+			default:
+				return body, fmt.Errorf("unknown branching model field %s", c.Field)
+			}
+
+		case len(parts) == 3 && parts[0] == "branch_types":
+			i, ok := branchTypes[parts[1]]
+			if !ok {
+				i = len(body.BranchTypes)
+				branchTypes[parts[1]] = i
+				body.BranchTypes = append(body.BranchTypes, bitbucketBranchType{Kind: parts[1]})
+			}
+
+			switch parts[2] {
+			case "enabled":
+				b, err := parseBool(c.Field, value)
+				if err != nil {
+					return body, err
+				}
+				body.BranchTypes[i].Enabled = &b
+			case "prefix":
+				body.BranchTypes[i].Prefix = &value
+			default:
+				return body, fmt.Errorf("unknown branching model field %s", c.Field)
+			}
+
+		default:
+			return body, fmt.Errorf("unknown branching model field %s", c.Field)
+		}
+	}
+	return body, nil
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeSettingChanges(t *testing.T) {
+	src := BranchingModel{
+		Development: BranchingModelBranch{Name: "develop"},
+		Production:  BranchingModelBranch{UseMainBranch: true, Enabled: true},
+		BranchTypes: []BranchType{{Kind: "feature", Enabled: true, Prefix: "feature/"}},
+	}
+	target := BranchingModel{
+		Development: BranchingModelBranch{UseMainBranch: true},
+		Production:  BranchingModelBranch{UseMainBranch: true},
+		BranchTypes: []BranchType{{Kind: "feature", Enabled: true, Prefix: "feat/"}, {Kind: "hotfix", Enabled: true, Prefix: "hotfix/"}},
+	}
+
+	messages := make([]string, 0)
+	for _, v := range MakeSettingChanges(src.Settings(), target.Settings()) {
+		messages = append(messages, v.Message())
+	}
+	assert.Equal(t, []string{
+		"Update: development.use_mainbranch: true => false",
+		`Update: development.branch: "" => develop`,
+		"Update: production.enabled: false => true",
+		"Same: production.use_mainbranch: true",
+		"Same: branch_types.feature.enabled: true",
+		"Update: branch_types.feature.prefix: feat/ => feature/",
+	}, messages)
+}
+
+func TestBranchingModel_Settings(t *testing.T) {
+	m := BranchingModel{
+		Development: BranchingModelBranch{Name: "develop", UseMainBranch: true},
+		Production:  BranchingModelBranch{Name: "production", Enabled: true},
+		BranchTypes: []BranchType{{Kind: "feature", Enabled: true, Prefix: "feature/"}, {Kind: "hotfix", Prefix: "hotfix/"}},
+	}
+	assert.Equal(t, []Setting{
+		{Field: "development.use_mainbranch", Value: "true"},
+		{Field: "production.enabled", Value: "true"},
+		{Field: "production.use_mainbranch", Value: "false"},
+		{Field: "production.branch", Value: "production"},
+		{Field: "branch_types.feature.enabled", Value: "true"},
+		{Field: "branch_types.feature.prefix", Value: "feature/"},
+		{Field: "branch_types.hotfix.enabled", Value: "false"},
+	}, m.Settings())
+}
+
+func TestBitbucketApi_BranchingModel(t *testing.T) {
+	requests := make([]string, 0)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/repositories/ws/repo/branching-model/settings", r.URL.Path)
+		b, _ := io.ReadAll(r.Body)
+		requests = append(requests, fmt.Sprintf("%s %s", r.Method, b))
+		fmt.Fprint(w, `{
+			"development": {"is_valid": true, "name": "develop", "use_mainbranch": false},
+			"production": {"is_valid": true, "name": null, "use_mainbranch": true, "enabled": false},
+			"branch_types": [{"kind": "feature", "enabled": true, "prefix": "feature/"}, {"kind": "bugfix", "enabled": false}]
+		}`)
+	}))
+	defer ts.Close()
+
+	ba := NewBitbucketApi(http.DefaultClient, "user", "pass", WithBaseUrl(ts.URL))
+	ctx := context.Background()
+
+	got, err := ba.GetBranchingModel(ctx, "ws", "repo")
+	assert.NoError(t, err)
+	assert.Equal(t, BranchingModel{
+		Development: BranchingModelBranch{Name: "develop"},
+		Production:  BranchingModelBranch{UseMainBranch: true},
+		BranchTypes: []BranchType{{Kind: "feature", Enabled: true, Prefix: "feature/"}, {Kind: "bugfix"}},
+	}, got)
+
+	err = ba.UpdateBranchingModel(ctx, "ws", "repo", []SettingChange{
+		{Field: "production.enabled", Current: "false", After: "true"},
+		{Field: "branch_types.bugfix.prefix", After: "fix/"},
+		{Field: "branch_types.bugfix.enabled", Current: "false", After: "true"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `PUT {"production":{"enabled":true},"branch_types":[{"kind":"bugfix","enabled":true,"prefix":"fix/"}]}`, requests[1])
+
+	err = ba.UpdateBranchingModel(ctx, "ws", "repo", []SettingChange{{Field: "development.enabled", After: "true"}})
+	assert.Error(t, err)
+	err = ba.UpdateBranchingModel(ctx, "ws", "repo", []SettingChange{{Field: "production.use_mainbranch", After: "yes"}})
+	assert.Error(t, err)
+	assert.Len(t, requests, 2)
+}
@@ -0,0 +1,337 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	endpointBranchRestrictions = "/repositories/%s/%s/branch-restrictions"
+	endpointBranchRestriction  = "/repositories/%s/%s/branch-restrictions/%d"
+)
+
+// BranchRestrictionKind is what a branch restriction restricts or requires.
+type BranchRestrictionKind string
+
+const (
+	BranchRestrictionPush                                BranchRestrictionKind = "push"
+	BranchRestrictionForce                               BranchRestrictionKind = "force"
+	BranchRestrictionDelete                              BranchRestrictionKind = "delete"
+	BranchRestrictionRestrictMerges                      BranchRestrictionKind = "restrict_merges"
+	BranchRestrictionRequireApprovalsToMerge             BranchRestrictionKind = "require_approvals_to_merge"
+	BranchRestrictionRequireDefaultReviewerApprovals     BranchRestrictionKind = "require_default_reviewer_approvals_to_merge"
+	BranchRestrictionRequirePassingBuildsToMerge         BranchRestrictionKind = "require_passing_builds_to_merge"
+	BranchRestrictionRequireTasksToBeCompleted           BranchRestrictionKind = "require_tasks_to_be_completed"
+	BranchRestrictionRequireNoChangesRequested           BranchRestrictionKind = "require_no_changes_requested"
+	BranchRestrictionRequireCommitsBehind                BranchRestrictionKind = "require_commits_behind"
+	BranchRestrictionRequireAllDependenciesMerged        BranchRestrictionKind = "require_all_dependencies_merged"
+	BranchRestrictionResetApprovalsOnChange              BranchRestrictionKind = "reset_pullrequest_approvals_on_change"
+	BranchRestrictionSmartResetApprovals                 BranchRestrictionKind = "smart_reset_pullrequest_approvals"
+	BranchRestrictionResetChangesRequestedOnChange       BranchRestrictionKind = "reset_pullrequest_changes_requested_on_change"
+	BranchRestrictionEnforceMergeChecks                  BranchRestrictionKind = "enforce_merge_checks"
+	BranchRestrictionAllowAutoMergeWhenBuildsPass        BranchRestrictionKind = "allow_auto_merge_when_builds_pass"
+	BranchRestrictionRequireReviewGroupApprovalsToMerge  BranchRestrictionKind = "require_review_group_approvals_to_merge"
+	BranchRestrictionRequireApprovalsToMergeByRole       BranchRestrictionKind = "require_approvals_to_merge_by_role"
+	BranchRestrictionRequireNoUnresolvedPullRequestTasks BranchRestrictionKind = "require_no_unresolved_pullrequest_tasks"
+)
+
+var branchRestrictionKinds = []BranchRestrictionKind{
+	BranchRestrictionPush,
+	BranchRestrictionForce,
+	BranchRestrictionDelete,
+	BranchRestrictionRestrictMerges,
+	BranchRestrictionRequireApprovalsToMerge,
+	BranchRestrictionRequireDefaultReviewerApprovals,
+	BranchRestrictionRequirePassingBuildsToMerge,
+	BranchRestrictionRequireTasksToBeCompleted,
+	BranchRestrictionRequireNoChangesRequested,
+	BranchRestrictionRequireCommitsBehind,
+	BranchRestrictionRequireAllDependenciesMerged,
+	BranchRestrictionResetApprovalsOnChange,
+	BranchRestrictionSmartResetApprovals,
+	BranchRestrictionResetChangesRequestedOnChange,
+	BranchRestrictionEnforceMergeChecks,
+	BranchRestrictionAllowAutoMergeWhenBuildsPass,
+	BranchRestrictionRequireReviewGroupApprovalsToMerge,
+	BranchRestrictionRequireApprovalsToMergeByRole,
+	BranchRestrictionRequireNoUnresolvedPullRequestTasks,
+}
+
+func ParseBranchRestrictionKind(s string) (BranchRestrictionKind, error) {
+	for _, v := range branchRestrictionKinds {
+		if string(v) == s {
+			return v, nil
+		}
+	}
+	return "", fmt.Errorf("invalid branch restriction kind %q", s)
+}
+
+const (
+	// BranchMatchGlob matches branches by the glob pattern.
+	BranchMatchGlob = "glob"
+	// BranchMatchBranchingModel matches branches by the branch type of the branching model, e.g. development or release.
+	BranchMatchBranchingModel = "branching_model"
+)
+
+// BranchRestriction is a rule applied to branches matching the pattern or the branch type.
+// Users and groups are exempted from push, force, delete and restrict_merges, and ignored for other kinds.
+type BranchRestriction struct {
+	Id              int
+	Kind            BranchRestrictionKind
+	BranchMatchKind string
+	Pattern         string
+	BranchType      string
+	// Value is the number of approvals, builds or commits for require_* kinds.
+	Value  int
+	Users  []Account
+	Groups []string
+}
+
+// Branch describes the branches the restriction applies to.
+func (r BranchRestriction) Branch() string {
+	if r.BranchMatchKind == BranchMatchBranchingModel {
+		return "type:" + r.BranchType
+	}
+	return r.Pattern
+}
+
+// key identifies the restriction regardless of the repository.
+func (r BranchRestriction) key() string {
+	return string(r.Kind) + " " + r.Branch()
+}
+
+func (r BranchRestriction) userIds() []string {
+	ids := make([]string, 0, len(r.Users))
+	for _, v := range r.Users {
+		ids = append(ids, v.Uuid)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (r BranchRestriction) groupIds() []string {
+	ids := append([]string{}, r.Groups...)
+	sort.Strings(ids)
+	return ids
+}
+
+// equal reports whether the restrictions have the same settings except the id.
+func (r BranchRestriction) equal(o BranchRestriction) bool {
+	return r.key() == o.key() &&
+		r.Value == o.Value &&
+		strings.Join(r.userIds(), ",") == strings.Join(o.userIds(), ",") &&
+		strings.Join(r.groupIds(), ",") == strings.Join(o.groupIds(), ",")
+}
+
+// Summary describes value and exempted users and groups of the restriction.
+func (r BranchRestriction) Summary() string {
+	parts := make([]string, 0)
+	if r.Value != 0 {
+		parts = append(parts, fmt.Sprintf("value=%d", r.Value))
+	}
+	if len(r.Users) > 0 {
+		names := make([]string, 0, len(r.Users))
+		for _, v := range r.Users {
+			name := v.Nickname
+			if name == "" {
+				name = v.Uuid
+			}
+			names = append(names, name)
+		}
+		parts = append(parts, "users="+strings.Join(names, "|"))
+	}
+	if len(r.Groups) > 0 {
+		parts = append(parts, "groups="+strings.Join(r.Groups, "|"))
+	}
+	return strings.Join(parts, " ")
+}
+
+// BranchRestrictionOperation is an operation to make a branch restriction of the target the same as the source.
+type BranchRestrictionOperation struct {
+	current BranchRestriction
+	after   BranchRestriction
+
+	add    bool
+	remove bool
+	update bool
+}
+
+func NewRemoveBranchRestrictionOperation(r BranchRestriction) BranchRestrictionOperation {
+	return BranchRestrictionOperation{
+		current: r,
+		remove:  true,
+	}
+}
+
+func (o BranchRestrictionOperation) Same() bool {
+	return !o.add && !o.update && !o.remove
+}
+
+func (o BranchRestrictionOperation) Message() string {
+	switch {
+	case o.update:
+		return fmt.Sprintf("Update: %s %s (%s) => (%s)", o.after.Kind, o.after.Branch(), o.current.Summary(), o.after.Summary())
+	case o.add:
+		return fmt.Sprintf("Add: %s %s (%s)", o.after.Kind, o.after.Branch(), o.after.Summary())
+	case o.remove:
+		return fmt.Sprintf("Remove: %s %s (%s)", o.current.Kind, o.current.Branch(), o.current.Summary())
+	default:
+		return fmt.Sprintf("Same: %s %s (%s)", o.after.Kind, o.after.Branch(), o.after.Summary())
+	}
+}
+
+// MakeBranchRestrictionOperationList makes operations to mirror source branch restrictions to the target.
+// Restrictions are matched by kind and branches.
+func MakeBranchRestrictionOperationList(srcRestrictions, targetRestrictions []BranchRestriction) []BranchRestrictionOperation {
+	targetMap := map[string]BranchRestriction{}
+	for _, v := range targetRestrictions {
+		targetMap[v.key()] = v
+	}
+	srcMap := map[string]BranchRestriction{}
+	for _, v := range srcRestrictions {
+		srcMap[v.key()] = v
+	}
+
+	result := make([]BranchRestrictionOperation, 0)
+	for k, vs := range srcMap {
+		if vt, ok := targetMap[k]; ok {
+			result = append(result, BranchRestrictionOperation{
+				current: vt,
+				after:   vs,
+				update:  !vs.equal(vt),
+			})
+		} else {
+			result = append(result, BranchRestrictionOperation{
+				after: vs,
+				add:   true,
+			})
+		}
+	}
+	for k, vt := range targetMap {
+		if _, ok := srcMap[k]; !ok {
+			result = append(result, NewRemoveBranchRestrictionOperation(vt))
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].key() < result[j].key()
+	})
+	return result
+}
+
+func (o BranchRestrictionOperation) key() string {
+	if o.remove {
+		return o.current.key()
+	}
+	return o.after.key()
+}
+
+type bitbucketBranchRestriction struct {
+	Type            string           `json:"type"`
+	Id              int              `json:"id"`
+	Kind            string           `json:"kind"`
+	BranchMatchKind string           `json:"branch_match_kind"`
+	BranchType      string           `json:"branch_type,omitempty"`
+	Pattern         string           `json:"pattern"`
+	Value           *int             `json:"value,omitempty"`
+	Users           []bitbucketUser  `json:"users"`
+	Groups          []bitbucketGroup `json:"groups"`
+}
+
+func (v bitbucketBranchRestriction) restriction() BranchRestriction {
+	r := BranchRestriction{
+		Id:              v.Id,
+		Kind:            BranchRestrictionKind(v.Kind),
+		BranchMatchKind: v.BranchMatchKind,
+		Pattern:         v.Pattern,
+		BranchType:      v.BranchType,
+		Users:           make([]Account, 0),
+		Groups:          make([]string, 0),
+	}
+	if v.Value != nil {
+		r.Value = *v.Value
+	}
+	for _, u := range v.Users {
+		r.Users = append(r.Users, Account{Uuid: u.Uuid, Nickname: u.Nickname, DisplayName: u.DisplayName})
+	}
+	for _, g := range v.Groups {
+		r.Groups = append(r.Groups, g.Slug)
+	}
+	return r
+}
+
+// branchRestrictionRequest is the body to create or update a branch restriction.
+// Users and groups are referred only by UUID and slug.
+type branchRestrictionRequest struct {
+	Type            string              `json:"type"`
+	Kind            string              `json:"kind"`
+	BranchMatchKind string              `json:"branch_match_kind"`
+	BranchType      string              `json:"branch_type,omitempty"`
+	Pattern         string              `json:"pattern"`
+	Value           *int                `json:"value,omitempty"`
+	Users           []map[string]string `json:"users"`
+	Groups          []map[string]string `json:"groups"`
+}
+
+func newBranchRestrictionRequest(r BranchRestriction) branchRestrictionRequest {
+	v := branchRestrictionRequest{
+		Type:            "branchrestriction",
+		Kind:            string(r.Kind),
+		BranchMatchKind: r.BranchMatchKind,
+		Pattern:         r.Pattern,
+		Users:           make([]map[string]string, 0),
+		Groups:          make([]map[string]string, 0),
+	}
+	if v.BranchMatchKind == "" {
+		v.BranchMatchKind = BranchMatchGlob
+	}
+	if v.BranchMatchKind == BranchMatchBranchingModel {
+		v.BranchType = r.BranchType
+		v.Pattern = ""
+	}
+	if r.Value != 0 {
+		value := r.Value
+		v.Value = &value
+	}
+	for _, u := range r.Users {
+		v.Users = append(v.Users, map[string]string{"uuid": u.Uuid})
+	}
+	for _, g := range r.Groups {
+		v.Groups = append(v.Groups, map[string]string{"slug": g})
+	}
+	return v
+}
+
+// ListBranchRestrictions gets branch restrictions of a repository.
+func (ba *BitbucketApi) ListBranchRestrictions(ctx context.Context, workspace, repository string) ([]BranchRestriction, error) {
+	return listAll(ctx, ba, fmt.Sprintf(endpointBranchRestrictions, workspace, repository), ba.pageOptions, bitbucketBranchRestriction.restriction)
+}
+
+// UpdateBranchRestrictions creates, updates and deletes branch restrictions of a repository according to operations.
+// It stops at the first failure or cancellation of ctx with *UpdateError[BranchRestrictionOperation].
+func (ba *BitbucketApi) UpdateBranchRestrictions(ctx context.Context, workspace, repository string, operations []BranchRestrictionOperation) error {
+	return applyOperations(ctx, operations, func(ctx context.Context, v BranchRestrictionOperation) error {
+		switch {
+		case v.add, v.update:
+			body, err := json.Marshal(newBranchRestrictionRequest(v.after))
+			if err != nil {
+				return err
+			}
+			if v.add {
+				_, err = ba.do(ctx, fmt.Sprintf(endpointBranchRestrictions, workspace, repository), "POST", bytes.NewBuffer(body))
+			} else {
+				_, err = ba.do(ctx, fmt.Sprintf(endpointBranchRestriction, workspace, repository, v.current.Id), "PUT", bytes.NewBuffer(body))
+			}
+			return err
+
+		case v.remove:
+			_, err := ba.do(ctx, fmt.Sprintf(endpointBranchRestriction, workspace, repository, v.current.Id), "DELETE", nil)
+			return err
+		}
+		return nil
+	})
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeBranchRestrictionOperationList(t *testing.T) {
+	src := []BranchRestriction{
+		{Id: 1, Kind: BranchRestrictionPush, BranchMatchKind: BranchMatchGlob, Pattern: "main", Users: []Account{{Uuid: "{a}", Nickname: "alice"}}, Groups: []string{"admins"}},
+		{Id: 2, Kind: BranchRestrictionRequireApprovalsToMerge, BranchMatchKind: BranchMatchGlob, Pattern: "main", Value: 2},
+		{Id: 3, Kind: BranchRestrictionDelete, BranchMatchKind: BranchMatchBranchingModel, BranchType: "release"},
+	}
+	target := []BranchRestriction{
+		{Id: 11, Kind: BranchRestrictionPush, BranchMatchKind: BranchMatchGlob, Pattern: "main", Users: []Account{{Uuid: "{a}"}}, Groups: []string{"admins"}},
+		{Id: 12, Kind: BranchRestrictionRequireApprovalsToMerge, BranchMatchKind: BranchMatchGlob, Pattern: "main", Value: 1},
+		{Id: 13, Kind: BranchRestrictionForce, BranchMatchKind: BranchMatchGlob, Pattern: "*"},
+	}
+
+	got := MakeBranchRestrictionOperationList(src, target)
+	messages := make([]string, 0)
+	for _, v := range got {
+		messages = append(messages, v.Message())
+	}
+	assert.Equal(t, []string{
+		"Add: delete type:release ()",
+		"Remove: force * ()",
+		"Same: push main (users=alice groups=admins)",
+		"Update: require_approvals_to_merge main (value=1) => (value=2)",
+	}, messages)
+	assert.Equal(t, 13, got[1].current.Id)
+	assert.Equal(t, 12, got[3].current.Id)
+}
+
+func TestBitbucketApi_UpdateBranchRestrictions(t *testing.T) {
+	requests := make([]string, 0)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		requests = append(requests, fmt.Sprintf("%s %s %s", r.Method, r.URL.Path, b))
+		if r.Method == "DELETE" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		fmt.Fprint(w, `{}`)
+	}))
+	defer ts.Close()
+
+	ba := NewBitbucketApi(http.DefaultClient, "user", "pass", WithBaseUrl(ts.URL))
+	src := []BranchRestriction{
+		{Kind: BranchRestrictionPush, BranchMatchKind: BranchMatchGlob, Pattern: "main", Users: []Account{{Uuid: "{a}"}}, Groups: []string{"admins"}},
+		{Kind: BranchRestrictionRequireApprovalsToMerge, BranchMatchKind: BranchMatchBranchingModel, BranchType: "production", Value: 2},
+	}
+	target := []BranchRestriction{
+		{Id: 12, Kind: BranchRestrictionRequireApprovalsToMerge, BranchMatchKind: BranchMatchBranchingModel, BranchType: "production", Value: 1},
+		{Id: 13, Kind: BranchRestrictionForce, BranchMatchKind: BranchMatchGlob, Pattern: "*"},
+	}
+	err := ba.UpdateBranchRestrictions(context.Background(), "ws", "repo", MakeBranchRestrictionOperationList(src, target))
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{
+		`DELETE /repositories/ws/repo/branch-restrictions/13 `,
+		`POST /repositories/ws/repo/branch-restrictions {"type":"branchrestriction","kind":"push","branch_match_kind":"glob","pattern":"main","users":[{"uuid":"{a}"}],"groups":[{"slug":"admins"}]}`,
+		`PUT /repositories/ws/repo/branch-restrictions/12 {"type":"branchrestriction","kind":"require_approvals_to_merge","branch_match_kind":"branching_model","branch_type":"production","pattern":"","value":2,"users":[],"groups":[]}`,
+	}, requests)
+}
+
+func TestBitbucketApi_ListBranchRestrictions(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/repositories/ws/repo/branch-restrictions", r.URL.Path)
+		res := map[string]any{
+			"values": []any{
+				map[string]any{"id": 1, "kind": "push", "branch_match_kind": "glob", "pattern": "main", "users": []any{map[string]any{"uuid": "{a}", "nickname": "alice"}}, "groups": []any{map[string]any{"slug": "admins"}}},
+				map[string]any{"id": 2, "kind": "require_approvals_to_merge", "branch_match_kind": "glob", "pattern": "main", "value": 2, "users": []any{}, "groups": []any{}},
+			},
+		}
+		json.NewEncoder(w).Encode(res)
+	}))
+	defer ts.Close()
+
+	ba := NewBitbucketApi(http.DefaultClient, "user", "pass", WithBaseUrl(ts.URL))
+	got, err := ba.ListBranchRestrictions(context.Background(), "ws", "repo")
+	assert.NoError(t, err)
+	assert.Equal(t, []BranchRestriction{
+		{Id: 1, Kind: BranchRestrictionPush, BranchMatchKind: BranchMatchGlob, Pattern: "main", Users: []Account{{Uuid: "{a}", Nickname: "alice"}}, Groups: []string{"admins"}},
+		{Id: 2, Kind: BranchRestrictionRequireApprovalsToMerge, BranchMatchKind: BranchMatchGlob, Pattern: "main", Value: 2, Users: []Account{}, Groups: []string{}},
+	}, got)
+}
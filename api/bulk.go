@@ -0,0 +1,197 @@
+package api
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// PermissionTypeNone in a change means the permission should be removed.
+const PermissionTypeNone PermissionType = "none"
+
+// Change is a requested permission of a principal on a repository.
+type Change struct {
+	Line       int            `json:"-"`
+	Workspace  string         `json:"workspace"`
+	Repository string         `json:"repository"`
+	ObjectType ObjectType     `json:"type"`
+	Principal  string         `json:"principal"`
+	Permission PermissionType `json:"permission"`
+}
+
+func (c Change) validate() error {
+	if c.Workspace == "" || c.Repository == "" || c.Principal == "" {
+		return fmt.Errorf("line %d: workspace, repository and principal are required", c.Line)
+	}
+	if c.ObjectType != ObjectTypeUser && c.ObjectType != ObjectTypeGroup {
+		return fmt.Errorf("line %d: invalid principal type %q: must be user or group", c.Line, c.ObjectType)
+	}
+	if c.Permission != PermissionTypeNone && c.Permission.Level() == 0 {
+		return fmt.Errorf("line %d: invalid permission %q: must be one of read, write, admin, none", c.Line, c.Permission)
+	}
+	return nil
+}
+
+// duplicates finds changes of the same principal on the same repository,
+// which would be compared to the permissions before the other change is applied.
+type duplicates map[string]int
+
+func (d duplicates) check(c Change) error {
+	key := strings.Join([]string{c.Workspace, c.Repository, string(c.ObjectType), c.Principal}, "\x00")
+	if line, ok := d[key]; ok {
+		return fmt.Errorf("line %d: duplicate change of %s %s on %s/%s, first at line %d", c.Line, c.ObjectType, c.Principal, c.Workspace, c.Repository, line)
+	}
+	d[key] = c.Line
+	return nil
+}
+
+// ReadChangesCSV reads changes from CSV with columns:
+// workspace, repository, principal type, principal, permission or none.
+// The header row is skipped if it exists. Changes of the same principal on the same repository are rejected.
+func ReadChangesCSV(r io.Reader) ([]Change, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = 5
+	cr.TrimLeadingSpace = true
+
+	changes := make([]Change, 0)
+	seen := duplicates{}
+	for first := true; ; first = false {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if first && strings.EqualFold(record[0], "workspace") {
+			continue
+		}
+		// the line where the record starts, which differs from the record index after quoted fields with newlines
+		line, _ := cr.FieldPos(0)
+
+		c := Change{
+			Line:       line,
+			Workspace:  record[0],
+			Repository: record[1],
+			ObjectType: ObjectType(strings.ToLower(record[2])),
+			Principal:  record[3],
+			Permission: PermissionType(strings.ToLower(record[4])),
+		}
+		if err := c.validate(); err != nil {
+			return nil, err
+		}
+		if err := seen.check(c); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+
+	return changes, nil
+}
+
+// ReadChangesJSONLines reads changes from JSON objects separated by newlines.
+// Changes of the same principal on the same repository are rejected.
+func ReadChangesJSONLines(r io.Reader) ([]Change, error) {
+	changes := make([]Change, 0)
+	seen := duplicates{}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var c Change
+		if err := json.Unmarshal([]byte(text), &c); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		c.Line = line
+		c.ObjectType = ObjectType(strings.ToLower(string(c.ObjectType)))
+		c.Permission = PermissionType(strings.ToLower(string(c.Permission)))
+		if err := c.validate(); err != nil {
+			return nil, err
+		}
+		if err := seen.check(c); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// FindPermission finds the permission of the object by its id or name.
+func FindPermission(permissions []Permission, objectType ObjectType, key string) (Permission, bool) {
+	for _, v := range permissions {
+		if v.ObjectType != objectType {
+			continue
+		}
+		if v.ObjectId == key || v.ObjectName == key {
+			return v, true
+		}
+	}
+	return Permission{}, false
+}
+
+// Operation makes the operation to apply the change to current permissions of the repository.
+func (c Change) Operation(permissions []Permission) Operation {
+	current, ok := FindPermission(permissions, c.ObjectType, c.Principal)
+	if !ok {
+		current = Permission{
+			ObjectId:   c.Principal,
+			ObjectName: c.Principal,
+			ObjectType: c.ObjectType,
+		}
+	}
+
+	switch {
+	case c.Permission == PermissionTypeNone && ok:
+		return NewRemoveOperation(current)
+	case c.Permission == PermissionTypeNone:
+		return Operation{objectId: current.ObjectId, objectName: current.ObjectName, objectType: current.ObjectType}
+	case !ok:
+		current.PermissionType = c.Permission
+		return NewAddOperation(current)
+	case current.PermissionType != c.Permission:
+		return NewUpdateOperation(current, c.Permission)
+	default:
+		return Operation{
+			objectId:          current.ObjectId,
+			objectName:        current.ObjectName,
+			objectType:        current.ObjectType,
+			permissionCurrent: current.PermissionType,
+			permissionAfter:   current.PermissionType,
+		}
+	}
+}
+
+// ApplyOperation returns permissions after the operation is applied,
+// so that following changes are compared to the latest permissions.
+func ApplyOperation(permissions []Permission, o Operation) []Permission {
+	result := make([]Permission, 0, len(permissions)+1)
+	for _, v := range permissions {
+		if v.ObjectType == o.objectType && v.ObjectId == o.objectId {
+			if o.remove {
+				continue
+			}
+			v.PermissionType = o.permissionAfter
+		}
+		result = append(result, v)
+	}
+	if o.add {
+		result = append(result, Permission{
+			ObjectId:       o.objectId,
+			ObjectName:     o.objectName,
+			ObjectType:     o.objectType,
+			PermissionType: o.permissionAfter,
+		})
+	}
+	return result
+}
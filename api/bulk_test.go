@@ -0,0 +1,121 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadChangesCSV(t *testing.T) {
+	in := `workspace,repository,type,principal,permission
+myworkspace, repo-a, group, developer, write
+myworkspace,repo-b,USER,{1111},none
+`
+	got, err := ReadChangesCSV(strings.NewReader(in))
+	assert.NoError(t, err)
+	assert.Equal(t, []Change{
+		{Line: 2, Workspace: "myworkspace", Repository: "repo-a", ObjectType: ObjectTypeGroup, Principal: "developer", Permission: PermissionTypeWrite},
+		{Line: 3, Workspace: "myworkspace", Repository: "repo-b", ObjectType: ObjectTypeUser, Principal: "{1111}", Permission: PermissionTypeNone},
+	}, got)
+
+	// lines of the file are reported, not indexes of records
+	got, err = ReadChangesCSV(strings.NewReader(`myworkspace,repo-a,group,"developer
+team",write
+
+myworkspace,repo-b,user,{1111},read
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 4}, []int{got[0].Line, got[1].Line})
+
+	_, err = ReadChangesCSV(strings.NewReader("myworkspace,repo-a,team,developer,write\n"))
+	assert.Error(t, err)
+}
+
+func TestReadChanges_duplicate(t *testing.T) {
+	_, err := ReadChangesCSV(strings.NewReader(`myworkspace,repo-a,group,developer,write
+myworkspace,repo-b,group,developer,write
+myworkspace,repo-a,GROUP,developer,none
+`))
+	assert.EqualError(t, err, "line 3: duplicate change of group developer on myworkspace/repo-a, first at line 1")
+
+	_, err = ReadChangesJSONLines(strings.NewReader(`{"workspace":"myworkspace","repository":"repo-a","type":"user","principal":"{1111}","permission":"write"}
+{"workspace":"myworkspace","repository":"repo-a","type":"user","principal":"{1111}","permission":"read"}
+`))
+	assert.EqualError(t, err, "line 2: duplicate change of user {1111} on myworkspace/repo-a, first at line 1")
+}
+
+func TestReadChangesJSONLines(t *testing.T) {
+	in := `{"workspace":"myworkspace","repository":"repo-a","type":"group","principal":"developer","permission":"write"}
+
+{"workspace":"myworkspace","repository":"repo-b","type":"user","principal":"{1111}","permission":"none"}
+`
+	got, err := ReadChangesJSONLines(strings.NewReader(in))
+	assert.NoError(t, err)
+	assert.Equal(t, []Change{
+		{Line: 1, Workspace: "myworkspace", Repository: "repo-a", ObjectType: ObjectTypeGroup, Principal: "developer", Permission: PermissionTypeWrite},
+		{Line: 3, Workspace: "myworkspace", Repository: "repo-b", ObjectType: ObjectTypeUser, Principal: "{1111}", Permission: PermissionTypeNone},
+	}, got)
+
+	_, err = ReadChangesJSONLines(strings.NewReader(`{"workspace":"myworkspace","repository":"repo-a","type":"group","principal":"developer","permission":"owner"}`))
+	assert.Error(t, err)
+}
+
+func TestChange_Operation(t *testing.T) {
+	permissions := []Permission{
+		{ObjectId: "developer", ObjectName: "developer", ObjectType: ObjectTypeGroup, PermissionType: PermissionTypeRead},
+		{ObjectId: "{1111}", ObjectName: "john-doe", ObjectType: ObjectTypeUser, PermissionType: PermissionTypeAdmin},
+	}
+
+	tests := []struct {
+		name   string
+		change Change
+		want   string
+	}{
+		{
+			name:   "update",
+			change: Change{ObjectType: ObjectTypeGroup, Principal: "developer", Permission: PermissionTypeWrite},
+			want:   "Update: group developer READ => WRITE",
+		},
+		{
+			name:   "add",
+			change: Change{ObjectType: ObjectTypeGroup, Principal: "security", Permission: PermissionTypeRead},
+			want:   "Add: group security (READ)",
+		},
+		{
+			name:   "remove by name",
+			change: Change{ObjectType: ObjectTypeUser, Principal: "john-doe", Permission: PermissionTypeNone},
+			want:   "Remove: user john-doe (ADMIN)",
+		},
+		{
+			name:   "same",
+			change: Change{ObjectType: ObjectTypeUser, Principal: "{1111}", Permission: PermissionTypeAdmin},
+			want:   "Same: user john-doe (ADMIN)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.change.Operation(permissions).Message())
+		})
+	}
+
+	none := Change{ObjectType: ObjectTypeUser, Principal: "{2222}", Permission: PermissionTypeNone}.Operation(permissions)
+	assert.True(t, none.Same())
+}
+
+func TestApplyOperation(t *testing.T) {
+	permissions := []Permission{
+		{ObjectId: "developer", ObjectName: "developer", ObjectType: ObjectTypeGroup, PermissionType: PermissionTypeRead},
+		{ObjectId: "{1111}", ObjectName: "john-doe", ObjectType: ObjectTypeUser, PermissionType: PermissionTypeAdmin},
+	}
+
+	got := ApplyOperation(permissions, Change{ObjectType: ObjectTypeGroup, Principal: "developer", Permission: PermissionTypeWrite}.Operation(permissions))
+	got = ApplyOperation(got, Change{ObjectType: ObjectTypeUser, Principal: "john-doe", Permission: PermissionTypeNone}.Operation(got))
+	got = ApplyOperation(got, Change{ObjectType: ObjectTypeGroup, Principal: "security", Permission: PermissionTypeRead}.Operation(got))
+	assert.Equal(t, []Permission{
+		{ObjectId: "developer", ObjectName: "developer", ObjectType: ObjectTypeGroup, PermissionType: PermissionTypeWrite},
+		{ObjectId: "security", ObjectName: "security", ObjectType: ObjectTypeGroup, PermissionType: PermissionTypeRead},
+	}, got)
+	assert.Equal(t, PermissionTypeRead, permissions[0].PermissionType)
+}
@@ -0,0 +1,190 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Cache stores responses of GET requests on disk by account and URL.
+// Entries younger than the TTL are served without requests. Older entries with an ETag are revalidated with If-None-Match.
+type Cache struct {
+	dir string
+	ttl time.Duration
+	// refresh ignores stored entries and replaces them with fresh responses.
+	refresh bool
+}
+
+// NewCache creates a cache storing entries under dir.
+// With refresh, entries are always fetched again and stored.
+func NewCache(dir string, ttl time.Duration, refresh bool) *Cache {
+	return &Cache{
+		dir:     dir,
+		ttl:     ttl,
+		refresh: refresh,
+	}
+}
+
+type cacheEntry struct {
+	Url      string      `json:"url"`
+	ETag     string      `json:"etag,omitempty"`
+	StoredAt time.Time   `json:"stored_at"`
+	Header   http.Header `json:"header,omitempty"`
+	Body     []byte      `json:"body"`
+}
+
+func (c *Cache) fresh(entry cacheEntry) bool {
+	return time.Since(entry.StoredAt) < c.ttl
+}
+
+func hash(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:])
+}
+
+// accountDir is the directory of entries of the account, so that accounts do not see responses of others.
+func (c *Cache) accountDir(account string) string {
+	return filepath.Join(c.dir, hash(account)[:16])
+}
+
+func (c *Cache) file(account, rawUrl string) string {
+	return filepath.Join(c.accountDir(account), hash(rawUrl)+".json")
+}
+
+func (c *Cache) load(account, rawUrl string) (cacheEntry, bool) {
+	b, err := os.ReadFile(c.file(account, rawUrl))
+	if err != nil {
+		return cacheEntry{}, false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(b, &entry); err != nil || entry.Url != rawUrl {
+		return cacheEntry{}, false
+	}
+	return entry, true
+}
+
+func (c *Cache) store(account string, entry cacheEntry) error {
+	if err := os.MkdirAll(c.accountDir(account), 0o700); err != nil {
+		return err
+	}
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.file(account, entry.Url), b, 0o600)
+}
+
+// invalidate removes entries of the account whose URL is under the prefix.
+func (c *Cache) invalidate(account, prefix string) error {
+	files, err := os.ReadDir(c.accountDir(account))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		file := filepath.Join(c.accountDir(account), f.Name())
+		b, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		var entry cacheEntry
+		if err := json.Unmarshal(b, &entry); err == nil && !underPrefix(entry.Url, prefix) {
+			continue
+		}
+		if err := os.Remove(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func underPrefix(rawUrl, prefix string) bool {
+	if rawUrl == prefix {
+		return true
+	}
+	return strings.HasPrefix(rawUrl, prefix) && strings.ContainsAny(rawUrl[len(prefix):len(prefix)+1], "/?")
+}
+
+// repositoryPrefix returns /repositories/{workspace}/{repository} of the endpoint.
+// It returns an empty string, which matches all entries, if the endpoint is not of a repository.
+func repositoryPrefix(endpoint string) string {
+	p := strings.SplitN(endpoint, "?", 2)[0]
+	parts := strings.Split(strings.TrimPrefix(p, "/"), "/")
+	if len(parts) < 3 || parts[0] != "repositories" {
+		return ""
+	}
+	return "/" + strings.Join(parts[:3], "/")
+}
+
+// accountOf identifies the credentials of the authenticator. Secrets are included
+// so that wrong credentials are not hidden by cached responses, and they are hashed by the cache.
+func accountOf(auth Authenticator) string {
+	switch a := auth.(type) {
+	case BasicAuth:
+		return "basic:" + a.Username + ":" + a.Password
+	case BearerToken:
+		return "bearer:" + a.Token
+	case *OAuth2ClientCredentials:
+		return "oauth2:" + a.clientId + ":" + a.clientSecret
+	default:
+		return ""
+	}
+}
+
+// cachedGet serves the GET request from the cache, revalidating or fetching it if needed.
+func (ba BitbucketApi) cachedGet(ctx context.Context, endpoint string) ([]byte, http.Header, error) {
+	logger := ba.logger
+	if logger == nil {
+		logger = discardLogger
+	}
+	rawUrl := ba.baseUrl + endpoint
+	account := accountOf(ba.auth)
+
+	entry, ok := ba.cache.load(account, rawUrl)
+	if ok && ba.cache.refresh {
+		ok = false
+	}
+	if ok && ba.cache.fresh(entry) {
+		logger.DebugContext(ctx, "cache hit", slog.String("url", rawUrl))
+		return entry.Body, entry.Header, nil
+	}
+
+	var header http.Header
+	if ok && entry.ETag != "" {
+		header = http.Header{"If-None-Match": {entry.ETag}}
+	}
+	status, b, h, err := requestWithHeader(ctx, ba.hc, logger, ba.auth, rawUrl, "GET", nil, header)
+	if err != nil {
+		return nil, h, err
+	}
+
+	if status == http.StatusNotModified && ok {
+		logger.DebugContext(ctx, "cache revalidated", slog.String("url", rawUrl))
+		entry.StoredAt = time.Now()
+	} else {
+		entry = cacheEntry{
+			Url:      rawUrl,
+			ETag:     h.Get("ETag"),
+			StoredAt: time.Now(),
+			Header:   redactHeader(h),
+			Body:     b,
+		}
+	}
+	if err := ba.cache.store(account, entry); err != nil {
+		logger.WarnContext(ctx, "failed to store cache", slog.Any("error", err))
+	}
+
+	return entry.Body, entry.Header, nil
+}
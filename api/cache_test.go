@@ -0,0 +1,117 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newCountingServer serves users permissions with an ETag and counts requests by method and path.
+func newCountingServer(t *testing.T) (*httptest.Server, func(string) int) {
+	var mu sync.Mutex
+	counts := map[string]int{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		counts[r.Method+" "+r.URL.Path]++
+		if r.Header.Get("If-None-Match") != "" {
+			counts["revalidate"]++
+		}
+		mu.Unlock()
+
+		if r.Method != "GET" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fmt.Fprint(w, `{"values":[{"type":"repository_user_permission","permission":"read","user":{"type":"user","uuid":"{1234}","nickname":"john-doe"}}],"pagelen":10,"size":1}`)
+	}))
+	t.Cleanup(ts.Close)
+	return ts, func(k string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return counts[k]
+	}
+}
+
+const pathUsers = "/repositories/ws/repo/permissions-config/users"
+
+func TestBitbucketApi_cache(t *testing.T) {
+	ts, count := newCountingServer(t)
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	ba := NewBitbucketApi(http.DefaultClient, "user", "pass", WithBaseUrl(ts.URL), WithCache(NewCache(dir, time.Hour, false)))
+	for i := 0; i < 3; i++ {
+		got, err := ba.ListUserPermission(ctx, "ws", "repo")
+		assert.NoError(t, err)
+		assert.Len(t, got, 1)
+	}
+	assert.Equal(t, 1, count("GET "+pathUsers))
+
+	// another account does not share entries
+	other := NewBitbucketApi(http.DefaultClient, "other", "pass", WithBaseUrl(ts.URL), WithCache(NewCache(dir, time.Hour, false)))
+	_, err := other.ListUserPermission(ctx, "ws", "repo")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count("GET "+pathUsers))
+
+	// updates invalidate entries of the repository
+	err = ba.UpdatePermissions(ctx, "ws", "repo", []Operation{NewRemoveOperation(Permission{ObjectId: "{1234}", ObjectType: ObjectTypeUser})})
+	assert.NoError(t, err)
+	_, err = ba.ListUserPermission(ctx, "ws", "repo")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count("GET "+pathUsers))
+
+	// refresh ignores entries
+	refresh := NewBitbucketApi(http.DefaultClient, "user", "pass", WithBaseUrl(ts.URL), WithCache(NewCache(dir, time.Hour, true)))
+	_, err = refresh.ListUserPermission(ctx, "ws", "repo")
+	assert.NoError(t, err)
+	assert.Equal(t, 4, count("GET "+pathUsers))
+	assert.Equal(t, 0, count("revalidate"))
+}
+
+func TestBitbucketApi_cacheRevalidate(t *testing.T) {
+	ts, count := newCountingServer(t)
+	ctx := context.Background()
+
+	ba := NewBitbucketApi(http.DefaultClient, "user", "pass", WithBaseUrl(ts.URL), WithCache(NewCache(t.TempDir(), 0, false)))
+	for i := 0; i < 2; i++ {
+		got, err := ba.ListUserPermission(ctx, "ws", "repo")
+		assert.NoError(t, err)
+		assert.Equal(t, []Permission{{ObjectId: "{1234}", ObjectName: "john-doe", ObjectType: ObjectTypeUser, PermissionType: PermissionTypeRead}}, got)
+	}
+	assert.Equal(t, 2, count("GET "+pathUsers))
+	assert.Equal(t, 1, count("revalidate"))
+}
+
+func TestRepositoryPrefix(t *testing.T) {
+	assert.Equal(t, "/repositories/ws/repo", repositoryPrefix("/repositories/ws/repo/permissions-config/users/abc"))
+	assert.Equal(t, "/repositories/ws/repo", repositoryPrefix("/repositories/ws/repo?fields=slug"))
+	assert.Equal(t, "", repositoryPrefix("/user"))
+
+	assert.True(t, underPrefix("http://x/repositories/ws/repo/default-reviewers", "http://x/repositories/ws/repo"))
+	assert.False(t, underPrefix("http://x/repositories/ws/repo-2/default-reviewers", "http://x/repositories/ws/repo"))
+	assert.NotEqual(t, accountOf(BasicAuth{Username: "a"}), accountOf(BasicAuth{Username: "b"}))
+}
+
+func TestBitbucketApi_cacheCurrentUser(t *testing.T) {
+	ts, count := newCountingServer(t)
+	ctx := context.Background()
+
+	ba := NewBitbucketApi(http.DefaultClient, "user", "pass", WithBaseUrl(ts.URL), WithCache(NewCache(t.TempDir(), time.Hour, false)))
+	for i := 0; i < 2; i++ {
+		_, err := ba.GetCurrentUser(ctx)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 2, count("GET /user"))
+	assert.Equal(t, 0, count("revalidate"))
+}
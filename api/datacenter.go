@@ -0,0 +1,444 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	endpointDataCenterPermissionUsers    = "/rest/api/1.0/projects/%s/repos/%s/permissions/users"
+	endpointDataCenterPermissionGroups   = "/rest/api/1.0/projects/%s/repos/%s/permissions/groups"
+	endpointDataCenterUser               = "/rest/api/1.0/users/%s"
+	endpointDataCenterWhoami             = "/plugins/servlet/applinks/whoami"
+	endpointDataCenterReviewerConditions = "/rest/default-reviewers/1.0/projects/%s/repos/%s/conditions"
+	endpointDataCenterReviewerCondition  = "/rest/default-reviewers/1.0/projects/%s/repos/%s/condition"
+)
+
+// pagelenDataCenter is the default page size requested to Bitbucket Data Center.
+const pagelenDataCenter = 100
+
+// DataCenterApi is a client of Bitbucket Data Center (Server) REST API 1.0.
+type DataCenterApi struct {
+	hc          *http.Client
+	logger      *slog.Logger
+	pageOptions PageOptions
+
+	baseUrl string
+	auth    Authenticator
+}
+
+// NewDataCenterApi creates a client of Bitbucket Data Center at baseUrl (e.g. https://bitbucket.example.com).
+func NewDataCenterApi(
+	hc *http.Client,
+	baseUrl string,
+	auth Authenticator,
+	opts ...Option,
+) *DataCenterApi {
+	o := newOptions(baseUrl, opts)
+	if o.pagelen == 0 {
+		o.pagelen = pagelenDataCenter
+	}
+	return &DataCenterApi{
+		hc:          hc,
+		logger:      o.logger,
+		pageOptions: PageOptions{Pagelen: o.pagelen, Parallel: o.parallel},
+		baseUrl:     strings.TrimSuffix(o.baseUrl, "/"),
+		auth:        auth,
+	}
+}
+
+// Response from Bitbucket Data Center API
+
+// pagedResponse is a page of Bitbucket Data Center API.
+type pagedResponse[T any] struct {
+	Values        []T  `json:"values"`
+	Size          int  `json:"size"`
+	Limit         int  `json:"limit"`
+	IsLastPage    bool `json:"isLastPage"`
+	NextPageStart int  `json:"nextPageStart"`
+}
+
+type dataCenterUser struct {
+	Id          int    `json:"id"`
+	Name        string `json:"name"`
+	Slug        string `json:"slug"`
+	DisplayName string `json:"displayName"`
+}
+
+type dataCenterGroup struct {
+	Name string `json:"name"`
+}
+
+type dataCenterPermissionUser struct {
+	User       dataCenterUser `json:"user"`
+	Permission string         `json:"permission"`
+}
+
+type dataCenterPermissionGroup struct {
+	Group      dataCenterGroup `json:"group"`
+	Permission string          `json:"permission"`
+}
+
+type dataCenterRefMatcher struct {
+	Id   string `json:"id"`
+	Type struct {
+		Id string `json:"id"`
+	} `json:"type"`
+}
+
+type dataCenterReviewerCondition struct {
+	Id    int `json:"id"`
+	Scope struct {
+		Type string `json:"type"`
+	} `json:"scope"`
+	SourceRefMatcher  dataCenterRefMatcher `json:"sourceRefMatcher"`
+	TargetRefMatcher  dataCenterRefMatcher `json:"targetRefMatcher"`
+	Reviewers         []dataCenterUser     `json:"reviewers"`
+	RequiredApprovals int                  `json:"requiredApprovals"`
+}
+
+type dataCenterReviewerConditionRequest struct {
+	SourceMatcher     dataCenterRefMatcher `json:"sourceMatcher"`
+	TargetMatcher     dataCenterRefMatcher `json:"targetMatcher"`
+	Reviewers         []dataCenterUserId   `json:"reviewers"`
+	RequiredApprovals int                  `json:"requiredApprovals"`
+}
+
+type dataCenterUserId struct {
+	Id int `json:"id"`
+}
+
+func dataCenterPermission(p PermissionType) string {
+	return "REPO_" + strings.ToUpper(string(p))
+}
+
+func fromDataCenterPermission(p string) PermissionType {
+	return PermissionType(strings.ToLower(strings.TrimPrefix(p, "REPO_")))
+}
+
+func (da DataCenterApi) do(ctx context.Context, endpoint, method string, body io.Reader) ([]byte, error) {
+	b, _, err := request(ctx, da.hc, da.logger, da.auth, da.baseUrl+endpoint, method, body)
+	return b, err
+}
+
+// fetchPage gets the page of the endpoint. Pages of Bitbucket Data Center do not report the total size,
+// so they are fetched one by one following nextPageStart even if Parallel is set.
+func (da *DataCenterApi) fetchPage(ctx context.Context, endpoint string) (page, error) {
+	res, err := da.do(ctx, endpoint, "GET", nil)
+	if err != nil {
+		return page{}, err
+	}
+	var r pagedResponse[json.RawMessage]
+	if err := json.Unmarshal(res, &r); err != nil {
+		return page{}, err
+	}
+
+	p := page{Values: r.Values, Pagelen: r.Limit}
+	if !r.IsLastPage && len(r.Values) > 0 {
+		p.Next, err = setQuery(endpoint, "start", strconv.Itoa(r.NextPageStart))
+		if err != nil {
+			return page{}, err
+		}
+	}
+	return p, nil
+}
+
+func (da *DataCenterApi) withPagelen(endpoint string, pagelen int) string {
+	return withQuery(endpoint, "limit", strconv.Itoa(pagelen))
+}
+
+func (da *DataCenterApi) pageEndpoint(endpoint string, p, pagelen int) string {
+	if !strings.Contains(endpoint, "limit=") {
+		endpoint = da.withPagelen(endpoint, pagelen)
+	}
+	return withQuery(endpoint, "start", strconv.Itoa((p-1)*pagelen))
+}
+
+// GetCurrentUser gets the authenticated account. Scopes are not reported by Bitbucket Data Center.
+func (da *DataCenterApi) GetCurrentUser(ctx context.Context) (CurrentUser, error) {
+	res, err := da.do(ctx, endpointDataCenterWhoami, "GET", nil)
+	if err != nil {
+		return CurrentUser{}, err
+	}
+	name := strings.TrimSpace(string(res))
+	if name == "" {
+		return CurrentUser{}, fmt.Errorf("not authenticated")
+	}
+
+	user, err := da.getUser(ctx, name)
+	if err != nil {
+		return CurrentUser{}, err
+	}
+
+	return CurrentUser{Account: user.account()}, nil
+}
+
+func (da *DataCenterApi) getUser(ctx context.Context, slug string) (dataCenterUser, error) {
+	res, err := da.do(ctx, fmt.Sprintf(endpointDataCenterUser, url.PathEscape(slug)), "GET", nil)
+	if err != nil {
+		return dataCenterUser{}, err
+	}
+	var user dataCenterUser
+	err = json.Unmarshal(res, &user)
+	if err != nil {
+		return dataCenterUser{}, err
+	}
+	return user, nil
+}
+
+func (u dataCenterUser) account() Account {
+	return Account{
+		Uuid:        u.Name,
+		Nickname:    u.Name,
+		DisplayName: u.DisplayName,
+	}
+}
+
+// ListPermission gets permissions for a repository.
+// ObjectId is the user name or the group name.
+func (da *DataCenterApi) ListPermission(ctx context.Context, workspace, repository string) ([]Permission, error) {
+	permissions := make([]Permission, 0)
+
+	groups, err := listAll(ctx, da, fmt.Sprintf(endpointDataCenterPermissionGroups, workspace, repository), da.pageOptions, func(v dataCenterPermissionGroup) Permission {
+		return Permission{
+			ObjectId:       v.Group.Name,
+			ObjectName:     v.Group.Name,
+			ObjectType:     ObjectTypeGroup,
+			PermissionType: fromDataCenterPermission(v.Permission),
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	permissions = append(permissions, groups...)
+
+	users, err := listAll(ctx, da, fmt.Sprintf(endpointDataCenterPermissionUsers, workspace, repository), da.pageOptions, func(v dataCenterPermissionUser) Permission {
+		return Permission{
+			ObjectId:       v.User.Name,
+			ObjectName:     v.User.Name,
+			ObjectType:     ObjectTypeUser,
+			PermissionType: fromDataCenterPermission(v.Permission),
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	permissions = append(permissions, users...)
+
+	return permissions, nil
+}
+
+// UpdatePermissions updates permissions of a repository according to operations.
+// It stops at the first failure or cancellation of ctx with *UpdateError[Operation] reporting applied and skipped operations.
+func (da *DataCenterApi) UpdatePermissions(ctx context.Context, workspace, repository string, operations []Operation) error {
+	return applyOperations(ctx, operations, func(ctx context.Context, v Operation) error {
+		endpoint := fmt.Sprintf(endpointDataCenterPermissionUsers, workspace, repository)
+		if v.objectType == ObjectTypeGroup {
+			endpoint = fmt.Sprintf(endpointDataCenterPermissionGroups, workspace, repository)
+		}
+
+		q := url.Values{}
+		q.Set("name", v.objectId)
+
+		switch {
+		case v.update, v.add:
+			q.Set("permission", dataCenterPermission(v.permissionAfter))
+			_, err := da.do(ctx, endpoint+"?"+q.Encode(), "PUT", nil)
+			return err
+
+		case v.remove:
+			_, err := da.do(ctx, endpoint+"?"+q.Encode(), "DELETE", nil)
+			return err
+		}
+		return nil
+	})
+}
+
+func (da *DataCenterApi) listReviewerConditions(ctx context.Context, workspace, repository string) ([]dataCenterReviewerCondition, error) {
+	res, err := da.do(ctx, fmt.Sprintf(endpointDataCenterReviewerConditions, workspace, repository), "GET", nil)
+	if err != nil {
+		return nil, err
+	}
+	var conditions []dataCenterReviewerCondition
+	err = json.Unmarshal(res, &conditions)
+	if err != nil {
+		return nil, err
+	}
+	return conditions, nil
+}
+
+// ListDefaultReviewers gets reviewers of all default reviewer conditions for a repository,
+// including ones inherited from the project.
+func (da *DataCenterApi) ListDefaultReviewers(ctx context.Context, workspace, repository string) ([]Account, error) {
+	conditions, err := da.listReviewerConditions(ctx, workspace, repository)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := make([]Account, 0)
+	seen := map[string]bool{}
+	for _, c := range conditions {
+		for _, v := range c.Reviewers {
+			if seen[v.Name] {
+				continue
+			}
+			seen[v.Name] = true
+			accounts = append(accounts, v.account())
+		}
+	}
+
+	return accounts, nil
+}
+
+// DeleteDefaultReviewers deletes reviewers from default reviewer conditions of a repository.
+// Conditions that have no reviewers left are deleted. Reviewers only in conditions of the project are left.
+// It stops at the first failure or cancellation of ctx with *UpdateError[DefaultReviewerOperation].
+// - reviewers: list of the user name or slug
+func (da *DataCenterApi) DeleteDefaultReviewers(ctx context.Context, workspace, repository string, reviewers []string) ([]Account, error) {
+	accounts := make([]Account, 0)
+
+	conditions, err := da.listReviewerConditions(ctx, workspace, repository)
+	if err != nil {
+		return nil, err
+	}
+	repositoryConditions := make([]dataCenterReviewerCondition, 0, len(conditions))
+	for _, c := range conditions {
+		if c.Scope.Type == "REPOSITORY" {
+			repositoryConditions = append(repositoryConditions, c)
+		}
+	}
+
+	operations := make([]DefaultReviewerOperation, 0, len(reviewers))
+	for _, v := range reviewers {
+		operations = append(operations, DefaultReviewerOperation{reviewer: v, remove: true})
+	}
+
+	err = applyOperations(ctx, operations, func(ctx context.Context, v DefaultReviewerOperation) error {
+		for i, c := range repositoryConditions {
+			rest := make([]dataCenterUser, 0, len(c.Reviewers))
+			for _, u := range c.Reviewers {
+				if u.Name == v.reviewer || u.Slug == v.reviewer {
+					accounts = append(accounts, u.account())
+					continue
+				}
+				rest = append(rest, u)
+			}
+			if len(rest) == len(c.Reviewers) {
+				continue
+			}
+
+			c.Reviewers = rest
+			if c.RequiredApprovals > len(rest) {
+				c.RequiredApprovals = len(rest)
+			}
+			if err := da.putReviewerCondition(ctx, workspace, repository, c); err != nil {
+				return err
+			}
+			// later operations see the reviewers left
+			repositoryConditions[i] = c
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return accounts, nil
+}
+
+// putReviewerCondition changes the reviewers of the condition, or deletes it if it has no reviewers.
+func (da *DataCenterApi) putReviewerCondition(ctx context.Context, workspace, repository string, c dataCenterReviewerCondition) error {
+	endpoint := fmt.Sprintf(endpointDataCenterReviewerCondition+"/%d", workspace, repository, c.Id)
+	if len(c.Reviewers) == 0 {
+		_, err := da.do(ctx, endpoint, "DELETE", nil)
+		return err
+	}
+
+	ids := make([]dataCenterUserId, 0, len(c.Reviewers))
+	for _, v := range c.Reviewers {
+		ids = append(ids, dataCenterUserId{Id: v.Id})
+	}
+	body, err := json.Marshal(dataCenterReviewerConditionRequest{
+		SourceMatcher:     c.SourceRefMatcher,
+		TargetMatcher:     c.TargetRefMatcher,
+		Reviewers:         ids,
+		RequiredApprovals: c.RequiredApprovals,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = da.do(ctx, endpoint, "PUT", bytes.NewBuffer(body))
+	return err
+}
+
+// AddDefaultReviewers adds a default reviewer condition from any branch to any branch with the reviewers.
+// The condition is created with the first reviewer, and the others are added to it one by one.
+// It stops at the first failure or cancellation of ctx with *UpdateError[DefaultReviewerOperation].
+// - reviewers: list of the user slug
+func (da *DataCenterApi) AddDefaultReviewers(ctx context.Context, workspace, repository string, reviewers []string) ([]Account, error) {
+	accounts := make([]Account, 0)
+
+	operations := make([]DefaultReviewerOperation, 0, len(reviewers))
+	for _, v := range reviewers {
+		operations = append(operations, DefaultReviewerOperation{reviewer: v})
+	}
+
+	anyRef := dataCenterRefMatcher{Id: "ANY_REF_MATCHER_ID"}
+	anyRef.Type.Id = "ANY_REF"
+	condition := dataCenterReviewerCondition{
+		SourceRefMatcher: anyRef,
+		TargetRefMatcher: anyRef,
+	}
+
+	err := applyOperations(ctx, operations, func(ctx context.Context, v DefaultReviewerOperation) error {
+		user, err := da.getUser(ctx, v.reviewer)
+		if err != nil {
+			return err
+		}
+
+		if condition.Id != 0 {
+			c := condition
+			c.Reviewers = append(append([]dataCenterUser(nil), c.Reviewers...), user)
+			if err := da.putReviewerCondition(ctx, workspace, repository, c); err != nil {
+				return err
+			}
+			condition = c
+			accounts = append(accounts, user.account())
+			return nil
+		}
+
+		body, err := json.Marshal(dataCenterReviewerConditionRequest{
+			SourceMatcher: anyRef,
+			TargetMatcher: anyRef,
+			Reviewers:     []dataCenterUserId{{Id: user.Id}},
+		})
+		if err != nil {
+			return err
+		}
+		res, err := da.do(ctx, fmt.Sprintf(endpointDataCenterReviewerCondition, workspace, repository), "POST", bytes.NewBuffer(body))
+		if err != nil {
+			return err
+		}
+		var created dataCenterReviewerCondition
+		if err := json.Unmarshal(res, &created); err != nil {
+			return err
+		}
+		condition.Id = created.Id
+		condition.Reviewers = []dataCenterUser{user}
+		accounts = append(accounts, user.account())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return accounts, nil
+}
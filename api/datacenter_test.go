@@ -0,0 +1,140 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDataCenterApi_ListPermission(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rest/api/1.0/projects/PRJ/repos/myrepository/permissions/groups":
+			fmt.Fprint(w, `{"size":1,"limit":100,"isLastPage":true,"start":0,"values":[{"group":{"name":"developer"},"permission":"REPO_WRITE"}]}`)
+		case "/rest/api/1.0/projects/PRJ/repos/myrepository/permissions/users":
+			assert.Equal(t, "100", r.URL.Query().Get("limit"))
+			if r.URL.Query().Get("start") == "" {
+				fmt.Fprint(w, `{"size":1,"limit":1,"isLastPage":false,"start":0,"nextPageStart":1,"values":[{"user":{"id":1,"name":"john-doe","slug":"john-doe","displayName":"John Doe"},"permission":"REPO_ADMIN"}]}`)
+			} else {
+				assert.Equal(t, "1", r.URL.Query().Get("start"))
+				fmt.Fprint(w, `{"size":1,"limit":1,"isLastPage":true,"start":1,"values":[{"user":{"id":2,"name":"reader-1","slug":"reader-1","displayName":"reader 1"},"permission":"REPO_READ"}]}`)
+			}
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	da := NewDataCenterApi(http.DefaultClient, ts.URL+"/", BasicAuth{Username: "user", Password: "pass"})
+	got, err := da.ListPermission(context.Background(), "PRJ", "myrepository")
+	assert.NoError(t, err)
+	assert.Equal(t, []Permission{
+		{ObjectId: "developer", ObjectName: "developer", ObjectType: ObjectTypeGroup, PermissionType: PermissionTypeWrite},
+		{ObjectId: "john-doe", ObjectName: "john-doe", ObjectType: ObjectTypeUser, PermissionType: PermissionTypeAdmin},
+		{ObjectId: "reader-1", ObjectName: "reader-1", ObjectType: ObjectTypeUser, PermissionType: PermissionTypeRead},
+	}, got)
+}
+
+func TestDataCenterApi_UpdatePermissions(t *testing.T) {
+	type request struct {
+		method string
+		uri    string
+	}
+	got := make([]request, 0)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, request{method: r.Method, uri: r.URL.RequestURI()})
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	da := NewDataCenterApi(http.DefaultClient, ts.URL, BasicAuth{Username: "user", Password: "pass"})
+	err := da.UpdatePermissions(context.Background(), "PRJ", "myrepository", []Operation{
+		NewUpdateOperation(Permission{ObjectId: "developer", ObjectType: ObjectTypeGroup, PermissionType: PermissionTypeRead}, PermissionTypeAdmin),
+		NewAddOperation(Permission{ObjectId: "john-doe", ObjectType: ObjectTypeUser, PermissionType: PermissionTypeWrite}),
+		NewRemoveOperation(Permission{ObjectId: "reader-1", ObjectType: ObjectTypeUser, PermissionType: PermissionTypeRead}),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []request{
+		{method: "PUT", uri: "/rest/api/1.0/projects/PRJ/repos/myrepository/permissions/groups?name=developer&permission=REPO_ADMIN"},
+		{method: "PUT", uri: "/rest/api/1.0/projects/PRJ/repos/myrepository/permissions/users?name=john-doe&permission=REPO_WRITE"},
+		{method: "DELETE", uri: "/rest/api/1.0/projects/PRJ/repos/myrepository/permissions/users?name=reader-1"},
+	}, got)
+}
+
+func TestDataCenterApi_DeleteDefaultReviewers(t *testing.T) {
+	bodies := map[string]string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/rest/default-reviewers/1.0/projects/PRJ/repos/myrepository/conditions":
+			fmt.Fprint(w, `[
+				{"id":1,"scope":{"type":"REPOSITORY"},"sourceRefMatcher":{"id":"ANY_REF_MATCHER_ID","type":{"id":"ANY_REF"}},"targetRefMatcher":{"id":"refs/heads/main","type":{"id":"BRANCH"}},"reviewers":[{"id":1,"name":"john-doe"},{"id":2,"name":"reader-1"}],"requiredApprovals":2},
+				{"id":2,"scope":{"type":"REPOSITORY"},"sourceRefMatcher":{"id":"ANY_REF_MATCHER_ID","type":{"id":"ANY_REF"}},"targetRefMatcher":{"id":"ANY_REF_MATCHER_ID","type":{"id":"ANY_REF"}},"reviewers":[{"id":1,"name":"john-doe"}],"requiredApprovals":0},
+				{"id":3,"scope":{"type":"PROJECT"},"reviewers":[{"id":1,"name":"john-doe"}],"requiredApprovals":0}
+			]`)
+		case r.Method == "DELETE" && r.URL.Path == "/rest/default-reviewers/1.0/projects/PRJ/repos/myrepository/condition/1":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			b, _ := io.ReadAll(r.Body)
+			bodies[r.Method+" "+r.URL.Path] = string(b)
+		}
+	}))
+	defer ts.Close()
+
+	da := NewDataCenterApi(http.DefaultClient, ts.URL, BasicAuth{Username: "user", Password: "pass"})
+	_, err := da.DeleteDefaultReviewers(context.Background(), "PRJ", "myrepository", []string{"john-doe"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"PUT /rest/default-reviewers/1.0/projects/PRJ/repos/myrepository/condition/1":    `{"sourceMatcher":{"id":"ANY_REF_MATCHER_ID","type":{"id":"ANY_REF"}},"targetMatcher":{"id":"refs/heads/main","type":{"id":"BRANCH"}},"reviewers":[{"id":2}],"requiredApprovals":1}`,
+		"DELETE /rest/default-reviewers/1.0/projects/PRJ/repos/myrepository/condition/2": "",
+	}, bodies)
+
+	// deleting the last reviewer of condition 1 fails after john-doe is deleted
+	bodies = map[string]string{}
+	_, err = da.DeleteDefaultReviewers(context.Background(), "PRJ", "myrepository", []string{"john-doe", "reader-1", "other"})
+	var updateErr *UpdateError[DefaultReviewerOperation]
+	assert.ErrorAs(t, err, &updateErr)
+	assert.Equal(t, []DefaultReviewerOperation{{reviewer: "john-doe", remove: true}}, updateErr.Applied)
+	assert.Equal(t, "Remove: reviewer reader-1", updateErr.Failed.Message())
+	assert.Equal(t, []DefaultReviewerOperation{{reviewer: "other", remove: true}}, updateErr.Skipped)
+}
+
+func TestDataCenterApi_AddDefaultReviewers(t *testing.T) {
+	requests := make([]string, 0)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		requests = append(requests, fmt.Sprintf("%s %s %s", r.Method, r.URL.Path, b))
+		switch r.URL.Path {
+		case "/rest/api/1.0/users/john-doe":
+			fmt.Fprint(w, `{"id":1,"name":"john-doe","slug":"john-doe","displayName":"John Doe"}`)
+		case "/rest/api/1.0/users/reader-1":
+			fmt.Fprint(w, `{"id":2,"name":"reader-1","slug":"reader-1","displayName":"reader 1"}`)
+		case "/rest/api/1.0/users/unknown":
+			w.WriteHeader(http.StatusNotFound)
+		case "/rest/default-reviewers/1.0/projects/PRJ/repos/myrepository/condition":
+			fmt.Fprint(w, `{"id":10}`)
+		}
+	}))
+	defer ts.Close()
+
+	da := NewDataCenterApi(http.DefaultClient, ts.URL, BasicAuth{Username: "user", Password: "pass"})
+	got, err := da.AddDefaultReviewers(context.Background(), "PRJ", "myrepository", []string{"john-doe", "reader-1", "unknown", "other"})
+
+	var updateErr *UpdateError[DefaultReviewerOperation]
+	assert.ErrorAs(t, err, &updateErr)
+	assert.Nil(t, got)
+	assert.Equal(t, []DefaultReviewerOperation{{reviewer: "john-doe"}, {reviewer: "reader-1"}}, updateErr.Applied)
+	assert.Equal(t, "Add: reviewer unknown", updateErr.Failed.Message())
+	assert.Equal(t, []DefaultReviewerOperation{{reviewer: "other"}}, updateErr.Skipped)
+	assert.Equal(t, []string{
+		"GET /rest/api/1.0/users/john-doe ",
+		`POST /rest/default-reviewers/1.0/projects/PRJ/repos/myrepository/condition {"sourceMatcher":{"id":"ANY_REF_MATCHER_ID","type":{"id":"ANY_REF"}},"targetMatcher":{"id":"ANY_REF_MATCHER_ID","type":{"id":"ANY_REF"}},"reviewers":[{"id":1}],"requiredApprovals":0}`,
+		"GET /rest/api/1.0/users/reader-1 ",
+		`PUT /rest/default-reviewers/1.0/projects/PRJ/repos/myrepository/condition/10 {"sourceMatcher":{"id":"ANY_REF_MATCHER_ID","type":{"id":"ANY_REF"}},"targetMatcher":{"id":"ANY_REF_MATCHER_ID","type":{"id":"ANY_REF"}},"reviewers":[{"id":1},{"id":2}],"requiredApprovals":0}`,
+		"GET /rest/api/1.0/users/unknown ",
+	}, requests)
+}
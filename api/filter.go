@@ -0,0 +1,67 @@
+package api
+
+// Filter selects permissions and operations by object.
+// Zero value matches everything.
+type Filter struct {
+	// ObjectType limits objects to the type if not empty.
+	ObjectType ObjectType
+	// Include is shell patterns of object id or name. Objects must match one of them if not empty.
+	Include []string
+	// Exclude is shell patterns of object id or name. Objects matching any of them are excluded.
+	Exclude []string
+}
+
+// Match reports whether the permission passes the filter.
+func (f Filter) Match(p Permission) bool {
+	if f.ObjectType != "" && f.ObjectType != p.ObjectType {
+		return false
+	}
+
+	if len(f.Include) > 0 {
+		included := false
+		for _, v := range f.Include {
+			if p.Match(v) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+
+	for _, v := range f.Exclude {
+		if p.Match(v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// FilterPermissions returns permissions that pass the filter.
+func FilterPermissions(permissions []Permission, f Filter) []Permission {
+	result := make([]Permission, 0)
+	for _, v := range permissions {
+		if f.Match(v) {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// FilterOperations returns operations whose object passes the filter.
+func FilterOperations(operations []Operation, f Filter) []Operation {
+	result := make([]Operation, 0)
+	for _, v := range operations {
+		p := Permission{
+			ObjectId:   v.objectId,
+			ObjectName: v.objectName,
+			ObjectType: v.objectType,
+		}
+		if f.Match(p) {
+			result = append(result, v)
+		}
+	}
+	return result
+}
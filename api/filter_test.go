@@ -0,0 +1,59 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterPermissions(t *testing.T) {
+	permissions := []Permission{
+		{ObjectId: "developer", ObjectName: "developer", ObjectType: ObjectTypeGroup, PermissionType: PermissionTypeWrite},
+		{ObjectId: "administrator", ObjectName: "administrator", ObjectType: ObjectTypeGroup, PermissionType: PermissionTypeAdmin},
+		{ObjectId: "{1111}", ObjectName: "john-doe", ObjectType: ObjectTypeUser, PermissionType: PermissionTypeAdmin},
+		{ObjectId: "{2222}", ObjectName: "bot-ci", ObjectType: ObjectTypeUser, PermissionType: PermissionTypeWrite},
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []Permission
+	}{
+		{
+			name:   "no filter",
+			filter: Filter{},
+			want:   permissions,
+		},
+		{
+			name:   "only groups",
+			filter: Filter{ObjectType: ObjectTypeGroup},
+			want:   permissions[:2],
+		},
+		{
+			name:   "include by id and name",
+			filter: Filter{Include: []string{"dev*", "{1111}"}},
+			want:   []Permission{permissions[0], permissions[2]},
+		},
+		{
+			name:   "exclude bots",
+			filter: Filter{ObjectType: ObjectTypeUser, Exclude: []string{"bot-*"}},
+			want:   []Permission{permissions[2]},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, FilterPermissions(permissions, tt.filter))
+		})
+	}
+}
+
+func TestFilterOperations(t *testing.T) {
+	operations := []Operation{
+		NewAddOperation(Permission{ObjectId: "developer", ObjectName: "developer", ObjectType: ObjectTypeGroup, PermissionType: PermissionTypeRead}),
+		NewRemoveOperation(Permission{ObjectId: "{2222}", ObjectName: "bot-ci", ObjectType: ObjectTypeUser, PermissionType: PermissionTypeWrite}),
+	}
+
+	got := FilterOperations(operations, Filter{Exclude: []string{"bot-*"}})
+	assert.Equal(t, operations[:1], got)
+}
@@ -0,0 +1,190 @@
+package api
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+type RuleKind string
+
+const (
+	// RuleKindRequire requires the object to have at least the permission.
+	RuleKindRequire RuleKind = "require"
+	// RuleKindForbid forbids any permission for objects matching the pattern.
+	RuleKindForbid RuleKind = "forbid"
+	// RuleKindMaxPermission limits the permission of objects matching the pattern.
+	RuleKindMaxPermission RuleKind = "max-permission"
+	// RuleKindMaxCount limits the number of objects that have the permission or higher.
+	RuleKindMaxCount RuleKind = "max-count"
+)
+
+// LintRule is a policy for permissions of a repository.
+//
+// Pattern is a shell pattern matched against the object id or name.
+// For RuleKindRequire, Pattern must be the object id (group slug or user UUID).
+// Max is a pointer so that a max-count rule without max is rejected rather than allowing no objects.
+type LintRule struct {
+	Name       string         `mapstructure:"name"`
+	Kind       RuleKind       `mapstructure:"kind"`
+	ObjectType ObjectType     `mapstructure:"object_type"`
+	Pattern    string         `mapstructure:"pattern"`
+	Permission PermissionType `mapstructure:"permission"`
+	Max        *int           `mapstructure:"max"`
+}
+
+func (r LintRule) String() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return string(r.Kind)
+}
+
+// Validate checks that the rule has fields required by its kind and the pattern is well-formed.
+func (r LintRule) Validate() error {
+	switch r.Kind {
+	case RuleKindRequire, RuleKindMaxPermission:
+		if r.Pattern == "" || r.Permission.Level() == 0 {
+			return fmt.Errorf("rule %s: pattern and permission are required", r)
+		}
+		if r.Kind == RuleKindRequire && r.ObjectType == "" {
+			return fmt.Errorf("rule %s: object_type is required", r)
+		}
+	case RuleKindForbid:
+		if r.Pattern == "" {
+			return fmt.Errorf("rule %s: pattern is required", r)
+		}
+	case RuleKindMaxCount:
+		if r.Permission.Level() == 0 || r.Max == nil {
+			return fmt.Errorf("rule %s: permission and max are required", r)
+		}
+		if *r.Max < 0 {
+			return fmt.Errorf("rule %s: max must not be negative", r)
+		}
+	default:
+		return fmt.Errorf("rule %s: unknown kind %q", r, r.Kind)
+	}
+	// Permission.Match ignores errors of patterns, so a bad pattern would never match
+	if _, err := path.Match(r.Pattern, ""); err != nil {
+		return fmt.Errorf("rule %s: invalid pattern %q: %w", r, r.Pattern, err)
+	}
+	return nil
+}
+
+func (r LintRule) matches(p Permission) bool {
+	if r.ObjectType != "" && r.ObjectType != p.ObjectType {
+		return false
+	}
+	if r.Pattern == "" {
+		return true
+	}
+	return p.Match(r.Pattern)
+}
+
+// Violation is a permission that does not comply with a rule.
+// Fix is the operation to comply with the rule, or nil if it cannot be fixed automatically.
+type Violation struct {
+	Rule    LintRule
+	Message string
+	Fix     *Operation
+}
+
+// Lint evaluates permissions of a repository against rules.
+func Lint(permissions []Permission, rules []LintRule) []Violation {
+	violations := make([]Violation, 0)
+
+	for _, r := range rules {
+		switch r.Kind {
+		case RuleKindRequire:
+			found := false
+			for _, p := range permissions {
+				if p.ObjectType != r.ObjectType || p.ObjectId != r.Pattern {
+					continue
+				}
+				found = true
+				if p.PermissionType.Level() < r.Permission.Level() {
+					o := NewUpdateOperation(p, r.Permission)
+					violations = append(violations, Violation{
+						Rule:    r,
+						Message: fmt.Sprintf("%s %s has %s, requires %s", p.ObjectType, p.ObjectName, strings.ToUpper(string(p.PermissionType)), strings.ToUpper(string(r.Permission))),
+						Fix:     &o,
+					})
+				}
+			}
+			if !found {
+				o := NewAddOperation(Permission{
+					ObjectId:       r.Pattern,
+					ObjectName:     r.Pattern,
+					ObjectType:     r.ObjectType,
+					PermissionType: r.Permission,
+				})
+				violations = append(violations, Violation{
+					Rule:    r,
+					Message: fmt.Sprintf("%s %s is missing, requires %s", r.ObjectType, r.Pattern, strings.ToUpper(string(r.Permission))),
+					Fix:     &o,
+				})
+			}
+
+		case RuleKindForbid:
+			for _, p := range permissions {
+				if !r.matches(p) {
+					continue
+				}
+				o := NewRemoveOperation(p)
+				violations = append(violations, Violation{
+					Rule:    r,
+					Message: fmt.Sprintf("%s %s must not have any permission (%s)", p.ObjectType, p.ObjectName, strings.ToUpper(string(p.PermissionType))),
+					Fix:     &o,
+				})
+			}
+
+		case RuleKindMaxPermission:
+			for _, p := range permissions {
+				if !r.matches(p) || p.PermissionType.Level() <= r.Permission.Level() {
+					continue
+				}
+				o := NewUpdateOperation(p, r.Permission)
+				violations = append(violations, Violation{
+					Rule:    r,
+					Message: fmt.Sprintf("%s %s has %s, allowed up to %s", p.ObjectType, p.ObjectName, strings.ToUpper(string(p.PermissionType)), strings.ToUpper(string(r.Permission))),
+					Fix:     &o,
+				})
+			}
+
+		case RuleKindMaxCount:
+			names := make([]string, 0)
+			for _, p := range permissions {
+				if r.matches(p) && p.PermissionType.Level() >= r.Permission.Level() {
+					names = append(names, p.ObjectName)
+				}
+			}
+			if len(names) > *r.Max {
+				violations = append(violations, Violation{
+					Rule:    r,
+					Message: fmt.Sprintf("%d objects have %s or higher, allowed at most %d: %s", len(names), strings.ToUpper(string(r.Permission)), *r.Max, strings.Join(names, ", ")),
+				})
+			}
+		}
+	}
+
+	return violations
+}
+
+// FixOperations returns operations to fix violations.
+// When several violations target the same object, the first one wins.
+func FixOperations(violations []Violation) []Operation {
+	operations := make([]Operation, 0)
+	seen := map[string]bool{}
+	for _, v := range violations {
+		if v.Fix == nil {
+			continue
+		}
+		key := string(v.Fix.objectType) + ":" + v.Fix.objectId
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		operations = append(operations, *v.Fix)
+	}
+	return operations
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLint(t *testing.T) {
+	permissions := []Permission{
+		{ObjectId: "developer", ObjectName: "developer", ObjectType: ObjectTypeGroup, PermissionType: PermissionTypeWrite},
+		{ObjectId: "contractor-a", ObjectName: "contractor-a", ObjectType: ObjectTypeGroup, PermissionType: PermissionTypeWrite},
+		{ObjectId: "security", ObjectName: "security", ObjectType: ObjectTypeGroup, PermissionType: PermissionTypeRead},
+		{ObjectId: "{1111}", ObjectName: "john-doe", ObjectType: ObjectTypeUser, PermissionType: PermissionTypeAdmin},
+		{ObjectId: "{2222}", ObjectName: "bot-ci", ObjectType: ObjectTypeUser, PermissionType: PermissionTypeAdmin},
+	}
+
+	tests := []struct {
+		name        string
+		rules       []LintRule
+		wantMessage []string
+		wantFix     []Operation
+	}{
+		{
+			name: "compliant",
+			rules: []LintRule{
+				{Kind: RuleKindRequire, ObjectType: ObjectTypeGroup, Pattern: "security", Permission: PermissionTypeRead},
+				{Kind: RuleKindMaxCount, Permission: PermissionTypeAdmin, Max: intPtr(3)},
+			},
+			wantMessage: []string{},
+			wantFix:     []Operation{},
+		},
+		{
+			name: "no user admin",
+			rules: []LintRule{
+				{Kind: RuleKindMaxPermission, ObjectType: ObjectTypeUser, Pattern: "*", Permission: PermissionTypeWrite},
+			},
+			wantMessage: []string{
+				"user john-doe has ADMIN, allowed up to WRITE",
+				"user bot-ci has ADMIN, allowed up to WRITE",
+			},
+			wantFix: []Operation{
+				NewUpdateOperation(permissions[3], PermissionTypeWrite),
+				NewUpdateOperation(permissions[4], PermissionTypeWrite),
+			},
+		},
+		{
+			name: "require",
+			rules: []LintRule{
+				{Kind: RuleKindRequire, ObjectType: ObjectTypeGroup, Pattern: "security", Permission: PermissionTypeWrite},
+				{Kind: RuleKindRequire, ObjectType: ObjectTypeGroup, Pattern: "auditor", Permission: PermissionTypeRead},
+			},
+			wantMessage: []string{
+				"group security has READ, requires WRITE",
+				"group auditor is missing, requires READ",
+			},
+			wantFix: []Operation{
+				NewUpdateOperation(permissions[2], PermissionTypeWrite),
+				NewAddOperation(Permission{ObjectId: "auditor", ObjectName: "auditor", ObjectType: ObjectTypeGroup, PermissionType: PermissionTypeRead}),
+			},
+		},
+		{
+			name: "max count and contractor",
+			rules: []LintRule{
+				{Kind: RuleKindMaxCount, Permission: PermissionTypeAdmin, Max: intPtr(1)},
+				{Kind: RuleKindMaxPermission, ObjectType: ObjectTypeGroup, Pattern: "contractor-*", Permission: PermissionTypeRead},
+				{Kind: RuleKindForbid, Pattern: "contractor-*"},
+			},
+			wantMessage: []string{
+				"2 objects have ADMIN or higher, allowed at most 1: john-doe, bot-ci",
+				"group contractor-a has WRITE, allowed up to READ",
+				"group contractor-a must not have any permission (WRITE)",
+			},
+			wantFix: []Operation{
+				NewUpdateOperation(permissions[1], PermissionTypeRead),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Lint(permissions, tt.rules)
+			gotMessage := make([]string, 0)
+			for _, v := range got {
+				gotMessage = append(gotMessage, v.Message)
+			}
+			assert.Equal(t, tt.wantMessage, gotMessage)
+			assert.Equal(t, tt.wantFix, FixOperations(got))
+		})
+	}
+}
+
+func TestLintRule_Validate(t *testing.T) {
+	assert.NoError(t, LintRule{Kind: RuleKindForbid, Pattern: "bot-*"}.Validate())
+	assert.Error(t, LintRule{Kind: RuleKindRequire, Pattern: "security", Permission: PermissionTypeRead}.Validate())
+	assert.Error(t, LintRule{Kind: RuleKindMaxCount}.Validate())
+	assert.Error(t, LintRule{Kind: RuleKindMaxCount, Permission: PermissionTypeAdmin}.Validate())
+	assert.Error(t, LintRule{Kind: RuleKindMaxCount, Permission: PermissionTypeAdmin, Max: intPtr(-1)}.Validate())
+	assert.NoError(t, LintRule{Kind: RuleKindMaxCount, Permission: PermissionTypeAdmin, Max: intPtr(0)}.Validate())
+	assert.Error(t, LintRule{Kind: "unknown"}.Validate())
+	assert.ErrorIs(t, LintRule{Kind: RuleKindForbid, Pattern: "dev["}.Validate(), path.ErrBadPattern)
+	assert.ErrorIs(t, LintRule{Kind: RuleKindMaxCount, Pattern: "dev[", Permission: PermissionTypeAdmin, Max: intPtr(1)}.Validate(), path.ErrBadPattern)
+}
+
+func intPtr(n int) *int {
+	return &n
+}
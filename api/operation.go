@@ -74,7 +74,36 @@ func (o Operation) Message() string {
 	}
 }
 
+// Strategy decides how permissions of the source are merged into the target.
+type Strategy string
+
+const (
+	// StrategyMirror makes the target exactly the same as the source.
+	StrategyMirror Strategy = "mirror"
+	// StrategyAdditive adds and upgrades permissions but never removes or downgrades.
+	StrategyAdditive Strategy = "additive"
+	// StrategyMax only upgrades permissions the target already has (read -> write -> admin).
+	StrategyMax Strategy = "max"
+	// StrategyMin only downgrades or removes permissions.
+	StrategyMin Strategy = "min"
+)
+
+func ParseStrategy(s string) (Strategy, error) {
+	switch st := Strategy(s); st {
+	case StrategyMirror, StrategyAdditive, StrategyMax, StrategyMin:
+		return st, nil
+	default:
+		return "", fmt.Errorf("unknown strategy %q: must be one of mirror, additive, max, min", s)
+	}
+}
+
+// MakeOperationList makes operations to mirror source permissions to the target.
 func MakeOperationList(srcPermissions, targetPermissions []Permission) []Operation {
+	return MakeOperationListWithStrategy(srcPermissions, targetPermissions, StrategyMirror)
+}
+
+// MakeOperationListWithStrategy makes operations to merge source permissions into the target according to the strategy.
+func MakeOperationListWithStrategy(srcPermissions, targetPermissions []Permission, strategy Strategy) []Operation {
 	srcPermissionsMap := map[string]Permission{}
 	for _, v := range srcPermissions {
 		srcPermissionsMap[v.ObjectId] = v
@@ -123,13 +152,53 @@ func MakeOperationList(srcPermissions, targetPermissions []Permission) []Operati
 
 	result := make([]Operation, 0)
 	for _, v := range operations {
-		result = append(result, v)
+		if o, ok := v.withStrategy(strategy); ok {
+			result = append(result, o)
+		}
 	}
 	sort.Slice(result, func(i, j int) bool {
 		a := result[i]
 		b := result[j]
-		return strings.Compare(string(a.objectType), string(b.objectType)) < 0 || strings.Compare(string(a.objectId), string(b.objectId)) < 0
+		if a.objectType != b.objectType {
+			return strings.Compare(string(a.objectType), string(b.objectType)) < 0
+		}
+		return strings.Compare(string(a.objectId), string(b.objectId)) < 0
 	})
 
 	return result
 }
+
+// withStrategy turns the mirror operation into the one allowed by the strategy.
+// It returns false if the object should not appear in the target at all.
+func (o Operation) withStrategy(strategy Strategy) (Operation, bool) {
+	keep := Operation{
+		objectId:          o.objectId,
+		objectName:        o.objectName,
+		objectType:        o.objectType,
+		permissionCurrent: o.permissionCurrent,
+		permissionAfter:   o.permissionCurrent,
+	}
+
+	switch strategy {
+	case StrategyAdditive:
+		// existing grants are never revoked, including by lowering them
+		if o.remove || (o.update && o.permissionAfter.Level() < o.permissionCurrent.Level()) {
+			return keep, true
+		}
+	case StrategyMax:
+		if o.add {
+			return Operation{}, false
+		}
+		if o.remove || (o.update && o.permissionAfter.Level() < o.permissionCurrent.Level()) {
+			return keep, true
+		}
+	case StrategyMin:
+		if o.add {
+			return Operation{}, false
+		}
+		if o.update && o.permissionAfter.Level() > o.permissionCurrent.Level() {
+			return keep, true
+		}
+	}
+	return o, true
+}
@@ -140,3 +140,76 @@ func TestOperation(t *testing.T) {
 		})
 	}
 }
+
+func TestMakeOperationListWithStrategy(t *testing.T) {
+	srcPermissions := []Permission{
+		{ObjectId: "{a}", ObjectName: "upgrade", ObjectType: ObjectTypeUser, PermissionType: PermissionTypeAdmin},
+		{ObjectId: "{b}", ObjectName: "downgrade", ObjectType: ObjectTypeUser, PermissionType: PermissionTypeRead},
+		{ObjectId: "{c}", ObjectName: "add", ObjectType: ObjectTypeUser, PermissionType: PermissionTypeWrite},
+	}
+	targetPermissions := []Permission{
+		{ObjectId: "{a}", ObjectName: "upgrade", ObjectType: ObjectTypeUser, PermissionType: PermissionTypeRead},
+		{ObjectId: "{b}", ObjectName: "downgrade", ObjectType: ObjectTypeUser, PermissionType: PermissionTypeWrite},
+		{ObjectId: "{d}", ObjectName: "remove", ObjectType: ObjectTypeUser, PermissionType: PermissionTypeRead},
+	}
+
+	tests := []struct {
+		strategy    Strategy
+		wantMessage []string
+	}{
+		{
+			strategy: StrategyMirror,
+			wantMessage: []string{
+				"Update: user upgrade READ => ADMIN",
+				"Update: user downgrade WRITE => READ",
+				"Add: user add (WRITE)",
+				"Remove: user remove (READ)",
+			},
+		},
+		{
+			strategy: StrategyAdditive,
+			wantMessage: []string{
+				"Update: user upgrade READ => ADMIN",
+				"Same: user downgrade (WRITE)",
+				"Add: user add (WRITE)",
+				"Same: user remove (READ)",
+			},
+		},
+		{
+			strategy: StrategyMax,
+			wantMessage: []string{
+				"Update: user upgrade READ => ADMIN",
+				"Same: user downgrade (WRITE)",
+				"Same: user remove (READ)",
+			},
+		},
+		{
+			strategy: StrategyMin,
+			wantMessage: []string{
+				"Same: user upgrade (READ)",
+				"Update: user downgrade WRITE => READ",
+				"Remove: user remove (READ)",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.strategy), func(t *testing.T) {
+			got := MakeOperationListWithStrategy(srcPermissions, targetPermissions, tt.strategy)
+			gotMessage := make([]string, 0)
+			for _, v := range got {
+				gotMessage = append(gotMessage, v.Message())
+			}
+			assert.Equal(t, tt.wantMessage, gotMessage)
+		})
+	}
+}
+
+func TestParseStrategy(t *testing.T) {
+	got, err := ParseStrategy("max")
+	assert.NoError(t, err)
+	assert.Equal(t, StrategyMax, got)
+
+	_, err = ParseStrategy("merge")
+	assert.Error(t, err)
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"io"
+	"log/slog"
+)
+
+// discardLogger is the default logger, which logs nothing.
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// Option configures a client of Bitbucket.
+type Option func(*options)
+
+type options struct {
+	baseUrl  string
+	logger   *slog.Logger
+	pagelen  int
+	parallel int
+	cache    *Cache
+}
+
+// WithBaseUrl sets the base URL of the API, e.g. for a proxy or a mock server.
+func WithBaseUrl(baseUrl string) Option {
+	return func(o *options) {
+		o.baseUrl = baseUrl
+	}
+}
+
+// WithLogger sets the logger of requests. Nothing is logged by default.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithPagelen sets the number of values per page requested by list methods.
+func WithPagelen(pagelen int) Option {
+	return func(o *options) {
+		o.pagelen = pagelen
+	}
+}
+
+// WithParallelPages makes list methods fetch up to n pages concurrently
+// when the first page tells the total size.
+func WithParallelPages(n int) Option {
+	return func(o *options) {
+		o.parallel = n
+	}
+}
+
+// WithCache makes GET requests served from the cache. Only Bitbucket Cloud supports it.
+func WithCache(cache *Cache) Option {
+	return func(o *options) {
+		o.cache = cache
+	}
+}
+
+func newOptions(baseUrl string, opts []Option) options {
+	o := options{
+		baseUrl: baseUrl,
+		logger:  discardLogger,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
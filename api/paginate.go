@@ -0,0 +1,240 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// PageOptions controls pagination of list endpoints.
+type PageOptions struct {
+	// Pagelen is the number of values per page. The default of Bitbucket is used if 0.
+	Pagelen int
+	// MaxPages stops after fetching the number of pages. Unlimited if 0.
+	MaxPages int
+	// Parallel is the number of pages fetched concurrently after the first page.
+	// Pages are fetched one by one following next links if 1 or less, or if the response has no size.
+	Parallel int
+}
+
+// Pager is a client whose list endpoints are paginated: *BitbucketApi or *DataCenterApi.
+type Pager interface {
+	// fetchPage gets the page of the endpoint.
+	fetchPage(ctx context.Context, endpoint string) (page, error)
+	// withPagelen returns the endpoint requesting pagelen values per page.
+	withPagelen(endpoint string, pagelen int) string
+	// pageEndpoint returns the endpoint of the page number p counted from 1, with pagelen values per page.
+	pageEndpoint(endpoint string, p, pagelen int) string
+}
+
+// page is a page of a list endpoint with values not yet decoded.
+type page struct {
+	Values []json.RawMessage
+	// Next is the endpoint of the next page. Empty if it is the last page.
+	Next string
+	// Size is the total number of values of all pages. 0 if not reported.
+	Size    int
+	Pagelen int
+}
+
+// Paginate calls fn with each value of the list endpoint in order, following next links.
+// It stops early when fn returns false.
+func Paginate[T any](ctx context.Context, pager Pager, endpoint string, opts PageOptions, fn func(T) bool) error {
+	if opts.Pagelen > 0 {
+		endpoint = pager.withPagelen(endpoint, opts.Pagelen)
+	}
+	if opts.Parallel > 1 {
+		return paginateParallel(ctx, pager, endpoint, opts, fn)
+	}
+	return paginateFrom(ctx, pager, endpoint, 1, opts, fn)
+}
+
+// paginateFrom fetches pages one by one from the page at next.
+func paginateFrom[T any](ctx context.Context, pager Pager, next string, p int, opts PageOptions, fn func(T) bool) error {
+	for ; next != ""; p++ {
+		r, err := pager.fetchPage(ctx, next)
+		if err != nil {
+			return err
+		}
+
+		if ok, err := eachValue(r, fn); !ok || err != nil {
+			return err
+		}
+
+		if opts.MaxPages > 0 && p >= opts.MaxPages {
+			return nil
+		}
+		next = r.Next
+	}
+
+	return nil
+}
+
+// paginateParallel fetches the first page, then the rest of pages concurrently by page number
+// computed from size and pagelen of the first page.
+func paginateParallel[T any](ctx context.Context, pager Pager, endpoint string, opts PageOptions, fn func(T) bool) error {
+	first, err := pager.fetchPage(ctx, endpoint)
+	if err != nil {
+		return err
+	}
+	if ok, err := eachValue(first, fn); !ok || err != nil {
+		return err
+	}
+	if first.Next == "" || opts.MaxPages == 1 {
+		return nil
+	}
+
+	if first.Size == 0 || first.Pagelen == 0 {
+		return paginateFrom(ctx, pager, first.Next, 2, opts, fn)
+	}
+
+	pages := (first.Size + first.Pagelen - 1) / first.Pagelen
+	if opts.MaxPages > 0 && pages > opts.MaxPages {
+		pages = opts.MaxPages
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		page page
+		err  error
+	}
+	results := make([]chan result, pages+1)
+	for p := 2; p <= pages; p++ {
+		results[p] = make(chan result, 1)
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, opts.Parallel)
+	for p := 2; p <= pages; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				results[p] <- result{err: ctx.Err()}
+				return
+			}
+			defer func() { <-sem }()
+
+			r, err := pager.fetchPage(ctx, pager.pageEndpoint(endpoint, p, first.Pagelen))
+			results[p] <- result{page: r, err: err}
+		}(p)
+	}
+	// cancel and wait for requests in flight when returning early
+	defer wg.Wait()
+
+	for p := 2; p <= pages; p++ {
+		r := <-results[p]
+		if r.err != nil {
+			cancel()
+			return r.err
+		}
+		if ok, err := eachValue(r.page, fn); !ok || err != nil {
+			cancel()
+			return err
+		}
+	}
+
+	return nil
+}
+
+// eachValue decodes values of the page and calls fn with each of them.
+// It returns false if fn stopped or decoding failed.
+func eachValue[T any](r page, fn func(T) bool) (bool, error) {
+	for _, b := range r.Values {
+		var v T
+		if err := json.Unmarshal(b, &v); err != nil {
+			return false, err
+		}
+		if !fn(v) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// listAll collects values of all pages of the list endpoint converted by conv.
+func listAll[T any, R any](ctx context.Context, pager Pager, endpoint string, opts PageOptions, conv func(T) R) ([]R, error) {
+	values := make([]R, 0)
+	err := Paginate(ctx, pager, endpoint, opts, func(v T) bool {
+		values = append(values, conv(v))
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func (ba *BitbucketApi) fetchPage(ctx context.Context, endpoint string) (page, error) {
+	res, err := ba.do(ctx, endpoint, "GET", nil)
+	if err != nil {
+		return page{}, err
+	}
+	var r response[json.RawMessage]
+	if err := json.Unmarshal(res, &r); err != nil {
+		return page{}, err
+	}
+
+	p := page{Values: r.Values, Size: r.Size, Pagelen: r.Pagelen}
+	if r.Next != nil {
+		p.Next, err = ba.endpointOf(*r.Next)
+		if err != nil {
+			return page{}, err
+		}
+	}
+	return p, nil
+}
+
+func (ba *BitbucketApi) withPagelen(endpoint string, pagelen int) string {
+	return withQuery(endpoint, "pagelen", strconv.Itoa(pagelen))
+}
+
+func (ba *BitbucketApi) pageEndpoint(endpoint string, p, pagelen int) string {
+	if !strings.Contains(endpoint, "pagelen=") {
+		endpoint = ba.withPagelen(endpoint, pagelen)
+	}
+	return withQuery(endpoint, "page", strconv.Itoa(p))
+}
+
+// endpointOf returns the endpoint of the next link relative to the base URL.
+// Links of Bitbucket Cloud are also accepted so that a proxy or a mock can be used as the base URL.
+// Other links are rejected not to send credentials to unknown hosts.
+func (ba BitbucketApi) endpointOf(next string) (string, error) {
+	for _, prefix := range []string{ba.baseUrl, urlBitbucketApi} {
+		if strings.HasPrefix(next, prefix+"/") || strings.HasPrefix(next, prefix+"?") {
+			return strings.TrimPrefix(next, prefix), nil
+		}
+	}
+	if strings.HasPrefix(next, "/") {
+		return next, nil
+	}
+	return "", fmt.Errorf("next link %s is not under %s", next, ba.baseUrl)
+}
+
+func withQuery(endpoint, key, value string) string {
+	sep := "?"
+	if strings.Contains(endpoint, "?") {
+		sep = "&"
+	}
+	return endpoint + sep + key + "=" + value
+}
+
+// setQuery returns the endpoint with the value of key in the query replaced.
+func setQuery(endpoint, key, value string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set(key, value)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
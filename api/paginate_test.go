@@ -0,0 +1,257 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newPagingServer serves values 1..size of pagelen per page with next links on the server.
+// The size is omitted from responses unless withSize.
+func newPagingServer(t *testing.T, size int, withSize bool) (*httptest.Server, *[]string) {
+	var mu sync.Mutex
+	requests := make([]string, 0)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.URL.RequestURI())
+		mu.Unlock()
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 0 {
+			page = 1
+		}
+		pagelen, _ := strconv.Atoi(r.URL.Query().Get("pagelen"))
+		if pagelen == 0 {
+			pagelen = 10
+		}
+
+		values := "["
+		for i := (page-1)*pagelen + 1; i <= page*pagelen && i <= size; i++ {
+			if i > (page-1)*pagelen+1 {
+				values += ","
+			}
+			values += strconv.Itoa(i)
+		}
+		values += "]"
+
+		next := ""
+		if page*pagelen < size {
+			next = fmt.Sprintf(`,"next":"http://%s%s?page=%d&pagelen=%d"`, r.Host, r.URL.Path, page+1, pagelen)
+		}
+		if withSize {
+			next += fmt.Sprintf(`,"size":%d`, size)
+		}
+		fmt.Fprintf(w, `{"values":%s,"pagelen":%d%s}`, values, pagelen, next)
+	}))
+	t.Cleanup(ts.Close)
+	return ts, &requests
+}
+
+func TestPaginate(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         PageOptions
+		stopAt       int
+		want         []int
+		wantRequests []string
+	}{
+		{
+			name: "all pages",
+			opts: PageOptions{Pagelen: 2},
+			want: []int{1, 2, 3, 4, 5},
+			wantRequests: []string{
+				"/items?pagelen=2",
+				"/items?page=2&pagelen=2",
+				"/items?page=3&pagelen=2",
+			},
+		},
+		{
+			name:         "max pages",
+			opts:         PageOptions{Pagelen: 2, MaxPages: 2},
+			want:         []int{1, 2, 3, 4},
+			wantRequests: []string{"/items?pagelen=2", "/items?page=2&pagelen=2"},
+		},
+		{
+			name:         "early stop",
+			opts:         PageOptions{Pagelen: 2},
+			stopAt:       3,
+			want:         []int{1, 2, 3},
+			wantRequests: []string{"/items?pagelen=2", "/items?page=2&pagelen=2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, requests := newPagingServer(t, 5, true)
+			ba := NewBitbucketApi(http.DefaultClient, "user", "pass", WithBaseUrl(ts.URL))
+
+			got := make([]int, 0)
+			err := Paginate(context.Background(), ba, "/items", tt.opts, func(v int) bool {
+				got = append(got, v)
+				return v != tt.stopAt
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantRequests, *requests)
+		})
+	}
+}
+
+func TestPaginate_parallel(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         PageOptions
+		withSize     bool
+		stopAt       int
+		want         []int
+		wantRequests []string
+	}{
+		{
+			name:     "pages by number",
+			opts:     PageOptions{Pagelen: 2, Parallel: 2},
+			withSize: true,
+			want:     []int{1, 2, 3, 4, 5, 6, 7},
+			wantRequests: []string{
+				"/items?pagelen=2",
+				"/items?pagelen=2&page=2",
+				"/items?pagelen=2&page=3",
+				"/items?pagelen=2&page=4",
+			},
+		},
+		{
+			name:     "max pages",
+			opts:     PageOptions{Pagelen: 2, Parallel: 2, MaxPages: 2},
+			withSize: true,
+			want:     []int{1, 2, 3, 4},
+			wantRequests: []string{
+				"/items?pagelen=2",
+				"/items?pagelen=2&page=2",
+			},
+		},
+		{
+			name:     "early stop",
+			opts:     PageOptions{Pagelen: 2, Parallel: 1 << 10},
+			withSize: true,
+			stopAt:   1,
+			want:     []int{1},
+			wantRequests: []string{
+				"/items?pagelen=2",
+			},
+		},
+		{
+			name: "fallback to next links without size",
+			opts: PageOptions{Pagelen: 2, Parallel: 2},
+			want: []int{1, 2, 3, 4, 5, 6, 7},
+			wantRequests: []string{
+				"/items?pagelen=2",
+				"/items?page=2&pagelen=2",
+				"/items?page=3&pagelen=2",
+				"/items?page=4&pagelen=2",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, requests := newPagingServer(t, 7, tt.withSize)
+			ba := NewBitbucketApi(http.DefaultClient, "user", "pass", WithBaseUrl(ts.URL))
+
+			got := make([]int, 0)
+			err := Paginate(context.Background(), ba, "/items", tt.opts, func(v int) bool {
+				got = append(got, v)
+				return v != tt.stopAt
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.ElementsMatch(t, tt.wantRequests, *requests)
+		})
+	}
+}
+
+func TestPaginate_parallelError(t *testing.T) {
+	const pages = 20
+	var mu sync.Mutex
+	requested := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested++
+		mu.Unlock()
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		switch {
+		case page == 2:
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		case page > 2:
+			time.Sleep(20 * time.Millisecond)
+		}
+		fmt.Fprintf(w, `{"values":[%d],"pagelen":1,"size":%d,"next":"http://%s%s?page=%d"}`, page, pages, r.Host, r.URL.Path, page+1)
+	}))
+	defer ts.Close()
+	ba := NewBitbucketApi(http.DefaultClient, "user", "pass", WithBaseUrl(ts.URL))
+
+	err := Paginate(context.Background(), ba, "/items", PageOptions{Pagelen: 1, Parallel: 2}, func(v int) bool {
+		return true
+	})
+	assert.Error(t, err)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Less(t, requested, pages)
+}
+
+func TestBitbucketApi_endpointOf(t *testing.T) {
+	ba := NewBitbucketApi(http.DefaultClient, "user", "pass", WithBaseUrl("http://localhost:8080"))
+
+	got, err := ba.endpointOf("http://localhost:8080/repositories/ws/repo/default-reviewers?page=2")
+	assert.NoError(t, err)
+	assert.Equal(t, "/repositories/ws/repo/default-reviewers?page=2", got)
+
+	got, err = ba.endpointOf("https://api.bitbucket.org/2.0/repositories/ws/repo/default-reviewers?page=2")
+	assert.NoError(t, err)
+	assert.Equal(t, "/repositories/ws/repo/default-reviewers?page=2", got)
+
+	_, err = ba.endpointOf("https://evil.example.com/repositories?page=2")
+	assert.Error(t, err)
+}
+
+func TestPaginate_dataCenter(t *testing.T) {
+	requests := make([]string, 0)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RequestURI())
+		start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		const size = 5
+		values := make([]int, 0)
+		for i := start + 1; i <= start+limit && i <= size; i++ {
+			values = append(values, i)
+		}
+		b, _ := json.Marshal(values)
+		fmt.Fprintf(w, `{"values":%s,"size":%d,"limit":%d,"isLastPage":%t,"nextPageStart":%d}`, b, len(values), limit, start+limit >= size, start+limit)
+	}))
+	defer ts.Close()
+	da := NewDataCenterApi(http.DefaultClient, ts.URL, BasicAuth{Username: "user", Password: "pass"})
+
+	got := make([]int, 0)
+	err := Paginate(context.Background(), da, "/items", PageOptions{Pagelen: 2, MaxPages: 2}, func(v int) bool {
+		got = append(got, v)
+		return true
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3, 4}, got)
+	assert.Equal(t, []string{"/items?limit=2", "/items?limit=2&start=2"}, requests)
+
+	got = got[:0]
+	err = Paginate(context.Background(), da, "/items", PageOptions{Pagelen: 2, Parallel: 2}, func(v int) bool {
+		got = append(got, v)
+		return true
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, got)
+}
@@ -0,0 +1,313 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const redacted = "REDACTED"
+
+// redactedHeaders are headers whose values are credentials.
+var redactedHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization"}
+
+// redactedKeys are keys of JSON and form bodies whose values are secrets.
+var redactedKeys = []string{"access_token", "refresh_token", "client_secret", "password", "secret", "token"}
+
+// Interaction is a recorded pair of a request and its response.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	Url    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// key identifies requests to replay regardless of the host.
+// Secrets of the query are redacted, so that requests match the recorded ones whose secrets are redacted.
+func (r RecordedRequest) key() string {
+	u, err := url.Parse(r.Url)
+	if err != nil {
+		return r.Method + " " + r.Url
+	}
+	u, err = url.Parse(redactUrl(u))
+	if err != nil {
+		return r.Method + " " + r.Url
+	}
+	return r.Method + " " + u.RequestURI()
+}
+
+// Recorder is a http.RoundTripper saving every request and response to a directory,
+// with credentials redacted.
+type Recorder struct {
+	dir  string
+	next http.RoundTripper
+
+	mu    sync.Mutex
+	count int
+}
+
+// NewRecorder creates a recorder saving to dir. next defaults to http.DefaultTransport.
+func NewRecorder(dir string, next http.RoundTripper) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Recorder{
+		dir:  dir,
+		next: next,
+	}, nil
+}
+
+func (rec *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		b, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		reqBody = b
+		req.Body = io.NopCloser(bytes.NewReader(b))
+	}
+
+	res, err := rec.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resBody, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(resBody))
+
+	in := Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			Url:    redactUrl(req.URL),
+			Header: redactHeader(req.Header),
+			Body:   redactBody(string(reqBody)),
+		},
+		Response: RecordedResponse{
+			StatusCode: res.StatusCode,
+			Header:     redactHeader(res.Header),
+			Body:       redactBody(string(resBody)),
+		},
+	}
+	if err := rec.save(in); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+func (rec *Recorder) save(in Interaction) error {
+	b, err := json.MarshalIndent(in, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	rec.mu.Lock()
+	rec.count++
+	n := rec.count
+	rec.mu.Unlock()
+
+	u, _ := url.Parse(in.Request.Url)
+	name := strings.Trim(unsafeFileChars.ReplaceAllString(u.Path, "_"), "_")
+	if len(name) > 100 {
+		name = name[:100]
+	}
+	file := filepath.Join(rec.dir, fmt.Sprintf("%04d-%s-%s.json", n, in.Request.Method, name))
+
+	return os.WriteFile(file, b, 0o600)
+}
+
+func redactHeader(h http.Header) http.Header {
+	if len(h) == 0 {
+		return nil
+	}
+	c := h.Clone()
+	for _, k := range redactedHeaders {
+		if c.Get(k) != "" {
+			c.Set(k, redacted)
+		}
+	}
+	return c
+}
+
+// redactUrl returns the URL with secrets of the query and user info replaced.
+func redactUrl(u *url.URL) string {
+	c := *u
+	if c.User != nil {
+		c.User = url.User(redacted)
+	}
+	q := c.Query()
+	changed := false
+	for _, k := range redactedKeys {
+		if q.Has(k) {
+			q.Set(k, redacted)
+			changed = true
+		}
+	}
+	if changed {
+		c.RawQuery = q.Encode()
+	}
+	return c.String()
+}
+
+// redactBody replaces secrets of JSON or form encoded bodies.
+func redactBody(body string) string {
+	if body == "" {
+		return body
+	}
+
+	var v any
+	if err := json.Unmarshal([]byte(body), &v); err == nil {
+		if redactJSON(v) {
+			b, _ := json.Marshal(v)
+			return string(b)
+		}
+		return body
+	}
+
+	if form, err := url.ParseQuery(body); err == nil && strings.Contains(body, "=") {
+		changed := false
+		for _, k := range redactedKeys {
+			if form.Has(k) {
+				form.Set(k, redacted)
+				changed = true
+			}
+		}
+		if changed {
+			return form.Encode()
+		}
+	}
+
+	return body
+}
+
+// redactJSON replaces secrets of objects nested at any depth of the decoded JSON in place.
+// It reports whether any secret is replaced.
+func redactJSON(v any) bool {
+	changed := false
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			if slices.Contains(redactedKeys, k) {
+				v[k] = redacted
+				changed = true
+				continue
+			}
+			if redactJSON(e) {
+				changed = true
+			}
+		}
+	case []any:
+		for _, e := range v {
+			if redactJSON(e) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// Replayer is a http.RoundTripper responding with interactions recorded by Recorder without network access.
+// Requests are matched by method, path and query. Interactions of the same request are replayed in recorded order.
+type Replayer struct {
+	mu           sync.Mutex
+	interactions map[string][]Interaction
+}
+
+// NewReplayer loads interactions recorded in dir.
+func NewReplayer(dir string) (*Replayer, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no recorded interactions in %s", dir)
+	}
+	sort.Strings(files)
+
+	interactions := map[string][]Interaction{}
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		var in Interaction
+		if err := json.Unmarshal(b, &in); err != nil {
+			return nil, fmt.Errorf("invalid interaction %s: %w", f, err)
+		}
+		k := in.Request.key()
+		interactions[k] = append(interactions[k], in)
+	}
+
+	return &Replayer{
+		interactions: interactions,
+	}, nil
+}
+
+func (rep *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	k := RecordedRequest{Method: req.Method, Url: req.URL.String()}.key()
+
+	rep.mu.Lock()
+	queue := rep.interactions[k]
+	if len(queue) == 0 {
+		rep.mu.Unlock()
+		return nil, fmt.Errorf("no recorded response for %s", k)
+	}
+	in := queue[0]
+	// the last one is kept to answer repeated requests
+	if len(queue) > 1 {
+		rep.interactions[k] = queue[1:]
+	}
+	rep.mu.Unlock()
+
+	header := in.Response.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", in.Response.StatusCode, http.StatusText(in.Response.StatusCode)),
+		StatusCode:    in.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(in.Response.Body)),
+		ContentLength: int64(len(in.Response.Body)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecorderAndReplayer(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repositories/myworkspace/myrepository/permissions-config/groups":
+			fmt.Fprint(w, `{"values":[{"type":"repository_group_permission","permission":"write","group":{"type":"group","slug":"developer","name":"developer"}}],"pagelen":10,"size":1}`)
+		case "/repositories/myworkspace/myrepository/permissions-config/users":
+			fmt.Fprint(w, `{"values":[],"pagelen":10,"size":0}`)
+		}
+	}))
+	defer ts.Close()
+
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	rec, err := NewRecorder(dir, nil)
+	assert.NoError(t, err)
+	ba := NewBitbucketApi(&http.Client{Transport: rec}, "user", "secret-password", WithBaseUrl(ts.URL))
+	want, err := ba.ListPermission(ctx, "myworkspace", "myrepository")
+	assert.NoError(t, err)
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	assert.Len(t, files, 2)
+	for _, f := range files {
+		b, _ := os.ReadFile(f)
+		assert.NotContains(t, string(b), "Basic ")
+		assert.Contains(t, string(b), redacted)
+	}
+
+	ts.Close()
+
+	rep, err := NewReplayer(dir)
+	assert.NoError(t, err)
+	ba = NewBitbucketApi(&http.Client{Transport: rep}, "", "", WithBaseUrl("http://replay.invalid"))
+	got, err := ba.ListPermission(ctx, "myworkspace", "myrepository")
+	assert.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	_, err = ba.ListPermission(ctx, "myworkspace", "unknown")
+	assert.Error(t, err)
+}
+
+func TestRecorderAndReplayer_queryToken(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"values":[],"pagelen":10,"size":0}`)
+	}))
+	defer ts.Close()
+
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	rec, err := NewRecorder(dir, nil)
+	assert.NoError(t, err)
+	_, _, err = request(ctx, &http.Client{Transport: rec}, nil, BasicAuth{}, ts.URL+"/repositories/myworkspace?access_token=secret-token&pagelen=10", "GET", nil)
+	assert.NoError(t, err)
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	assert.Len(t, files, 1)
+	b, _ := os.ReadFile(files[0])
+	assert.NotContains(t, string(b), "secret-token")
+	assert.Contains(t, string(b), "access_token=REDACTED")
+
+	rep, err := NewReplayer(dir)
+	assert.NoError(t, err)
+	_, _, err = request(ctx, &http.Client{Transport: rep}, nil, BasicAuth{}, "http://replay.invalid/repositories/myworkspace?access_token=other-token&pagelen=10", "GET", nil)
+	assert.NoError(t, err)
+}
+
+func TestRedactBody(t *testing.T) {
+	assert.Equal(t, `{"access_token":"REDACTED","expires_in":7200}`, redactBody(`{"access_token":"abc","expires_in":7200}`))
+	assert.Equal(t, "grant_type=refresh_token&refresh_token=REDACTED", redactBody("grant_type=refresh_token&refresh_token=abc"))
+	assert.Equal(t, `{"permission":"write"}`, redactBody(`{"permission":"write"}`))
+	assert.Equal(t, `{"active":true,"config":{"secret":"REDACTED","url":"https://example.com"}}`, redactBody(`{"active":true,"config":{"secret":"abc","url":"https://example.com"}}`))
+	assert.Equal(t, `{"values":[{"secret":"REDACTED"}]}`, redactBody(`{"values":[{"secret":"abc"}]}`))
+}
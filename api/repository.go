@@ -0,0 +1,132 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+const endpointRepository = "/repositories/%s/%s"
+
+// ForkPolicy is who can fork a repository.
+type ForkPolicy string
+
+const (
+	ForkPolicyAllowForks    ForkPolicy = "allow_forks"
+	ForkPolicyNoPublicForks ForkPolicy = "no_public_forks"
+	ForkPolicyNoForks       ForkPolicy = "no_forks"
+)
+
+// ParseForkPolicy returns the fork policy of the name.
+func ParseForkPolicy(s string) (ForkPolicy, error) {
+	switch v := ForkPolicy(s); v {
+	case ForkPolicyAllowForks, ForkPolicyNoPublicForks, ForkPolicyNoForks:
+		return v, nil
+	}
+	return "", fmt.Errorf("invalid fork policy %q: must be one of %s, %s, %s", s, ForkPolicyAllowForks, ForkPolicyNoPublicForks, ForkPolicyNoForks)
+}
+
+// RepositorySettings is the properties of a repository, which are copied between repositories.
+type RepositorySettings struct {
+	Private     bool       `json:"is_private"`
+	ForkPolicy  ForkPolicy `json:"fork_policy"`
+	MainBranch  string     `json:"mainbranch"`
+	Language    string     `json:"language"`
+	Description string     `json:"description"`
+	HasIssues   bool       `json:"has_issues"`
+	HasWiki     bool       `json:"has_wiki"`
+}
+
+// Settings returns the fields of the repository compared by MakeSettingChanges.
+func (s RepositorySettings) Settings() []Setting {
+	return []Setting{
+		{Field: "is_private", Value: formatBool(s.Private)},
+		{Field: "fork_policy", Value: string(s.ForkPolicy)},
+		{Field: "mainbranch", Value: s.MainBranch},
+		{Field: "language", Value: s.Language},
+		{Field: "description", Value: s.Description},
+		{Field: "has_issues", Value: formatBool(s.HasIssues)},
+		{Field: "has_wiki", Value: formatBool(s.HasWiki)},
+	}
+}
+
+type bitbucketRepository struct {
+	IsPrivate  bool   `json:"is_private"`
+	ForkPolicy string `json:"fork_policy"`
+	MainBranch *struct {
+		Name string `json:"name"`
+	} `json:"mainbranch"`
+	Language    string `json:"language"`
+	Description string `json:"description"`
+	HasIssues   bool   `json:"has_issues"`
+	HasWiki     bool   `json:"has_wiki"`
+}
+
+// GetRepositorySettings gets the properties of a repository.
+func (ba *BitbucketApi) GetRepositorySettings(ctx context.Context, workspace, repository string) (RepositorySettings, error) {
+	res, err := ba.do(ctx, fmt.Sprintf(endpointRepository, workspace, repository), "GET", nil)
+	if err != nil {
+		return RepositorySettings{}, err
+	}
+
+	var v bitbucketRepository
+	if err := json.Unmarshal(res, &v); err != nil {
+		return RepositorySettings{}, err
+	}
+
+	s := RepositorySettings{
+		Private:     v.IsPrivate,
+		ForkPolicy:  ForkPolicy(v.ForkPolicy),
+		Language:    v.Language,
+		Description: v.Description,
+		HasIssues:   v.HasIssues,
+		HasWiki:     v.HasWiki,
+	}
+	if v.MainBranch != nil {
+		s.MainBranch = v.MainBranch.Name
+	}
+	return s, nil
+}
+
+// UpdateRepositorySettings changes properties of a repository.
+// Fields not in changes are kept.
+func (ba *BitbucketApi) UpdateRepositorySettings(ctx context.Context, workspace, repository string, changes []SettingChange) error {
+	body, err := repositoryBody(changes)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	_, err = ba.do(ctx, fmt.Sprintf(endpointRepository, workspace, repository), "PUT", bytes.NewBuffer(b))
+	return err
+}
+
+func repositoryBody(changes []SettingChange) (map[string]any, error) {
+	body := map[string]any{}
+	for _, c := range changes {
+		switch c.Field {
+		case "is_private", "has_issues", "has_wiki":
+			b, err := parseBool(c.Field, c.After)
+			if err != nil {
+				return nil, err
+			}
+			body[c.Field] = b
+		case "fork_policy":
+			p, err := ParseForkPolicy(c.After)
+			if err != nil {
+				return nil, err
+			}
+			body[c.Field] = p
+		case "mainbranch":
+			body[c.Field] = map[string]string{"name": c.After}
+		case "language", "description":
+			body[c.Field] = c.After
+		default:
+			return nil, fmt.Errorf("unknown repository field %s", c.Field)
+		}
+	}
+	return body, nil
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBitbucketApi_RepositorySettings(t *testing.T) {
+	requests := make([]string, 0)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/repositories/ws/repo", r.URL.Path)
+		b, _ := io.ReadAll(r.Body)
+		requests = append(requests, fmt.Sprintf("%s %s", r.Method, b))
+		fmt.Fprint(w, `{
+			"type": "repository", "full_name": "ws/repo", "is_private": true, "fork_policy": "no_public_forks",
+			"mainbranch": {"type": "branch", "name": "main"}, "language": "go", "description": "my repository",
+			"has_issues": false, "has_wiki": true
+		}`)
+	}))
+	defer ts.Close()
+
+	ba := NewBitbucketApi(http.DefaultClient, "user", "pass", WithBaseUrl(ts.URL))
+	ctx := context.Background()
+
+	got, err := ba.GetRepositorySettings(ctx, "ws", "repo")
+	assert.NoError(t, err)
+	assert.Equal(t, RepositorySettings{
+		Private:     true,
+		ForkPolicy:  ForkPolicyNoPublicForks,
+		MainBranch:  "main",
+		Language:    "go",
+		Description: "my repository",
+		HasWiki:     true,
+	}, got)
+
+	err = ba.UpdateRepositorySettings(ctx, "ws", "repo", []SettingChange{
+		{Field: "fork_policy", After: "no_forks"},
+		{Field: "mainbranch", After: "develop"},
+		{Field: "has_wiki", After: "false"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `PUT {"fork_policy":"no_forks","has_wiki":false,"mainbranch":{"name":"develop"}}`, requests[1])
+
+	err = ba.UpdateRepositorySettings(ctx, "ws", "repo", []SettingChange{{Field: "fork_policy", After: "anyone"}})
+	assert.Error(t, err)
+	err = ba.UpdateRepositorySettings(ctx, "ws", "repo", []SettingChange{{Field: "name", After: "other"}})
+	assert.Error(t, err)
+	assert.Len(t, requests, 2)
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Setting is a value of a field of repository settings such as the branching model.
+// Values are formatted as strings so that settings of any type are compared and shown the same way.
+type Setting struct {
+	Field string
+	Value string
+}
+
+// SettingChange is a change of a setting field of the target to the value of the source.
+type SettingChange struct {
+	Field   string
+	Current string
+	After   string
+}
+
+func (c SettingChange) Same() bool {
+	return c.Current == c.After
+}
+
+func (c SettingChange) Message() string {
+	if c.Same() {
+		return fmt.Sprintf("Same: %s: %s", c.Field, quoteEmpty(c.After))
+	}
+	return fmt.Sprintf("Update: %s: %s => %s", c.Field, quoteEmpty(c.Current), quoteEmpty(c.After))
+}
+
+func quoteEmpty(s string) string {
+	if s == "" {
+		return `""`
+	}
+	return s
+}
+
+// MakeSettingChanges compares settings of the source and the target by field, in order of the source.
+// Fields only in the source are changed from an empty value. Fields only in the target are kept.
+func MakeSettingChanges(src, target []Setting) []SettingChange {
+	targetMap := map[string]string{}
+	for _, v := range target {
+		targetMap[v.Field] = v.Value
+	}
+
+	changes := make([]SettingChange, 0, len(src))
+	for _, v := range src {
+		changes = append(changes, SettingChange{
+			Field:   v.Field,
+			Current: targetMap[v.Field],
+			After:   v.Value,
+		})
+	}
+	return changes
+}
+
+func formatBool(b bool) string {
+	return strconv.FormatBool(b)
+}
+
+func parseBool(field, s string) (bool, error) {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s %q: must be true or false", field, s)
+	}
+	return b, nil
+}
@@ -0,0 +1,260 @@
+package api
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// slowestRequests is the number of requests listed as slowest in the summary.
+const slowestRequests = 5
+
+// RequestTiming is the timing of a request. Phases are zero if they did not happen, e.g. for reused connections.
+type RequestTiming struct {
+	Method string
+	Url    string
+	// Endpoint is the method and the template of the path, which groups pages of a listing and requests to any repository.
+	Endpoint string
+	Status   int
+	Err      error
+
+	DNS     time.Duration
+	Connect time.Duration
+	TLS     time.Duration
+	// TTFB is the time from the start of the request to the first byte of the response.
+	TTFB time.Duration
+	// Total is the time until the response headers are read.
+	Total time.Duration
+	// Retry is true if the request is a retry of a failed attempt.
+	Retry bool
+}
+
+// Tracer is a http.RoundTripper measuring DNS, connect, TLS and time to first byte of every request.
+// Each timing is written to out as the request completes, and Summary reports all of them.
+type Tracer struct {
+	next  http.RoundTripper
+	out   io.Writer
+	start time.Time
+
+	mu      sync.Mutex
+	timings []RequestTiming
+}
+
+// NewTracer creates a tracer writing timings to out. next defaults to http.DefaultTransport.
+func NewTracer(next http.RoundTripper, out io.Writer) *Tracer {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if out == nil {
+		out = io.Discard
+	}
+	return &Tracer{
+		next:  next,
+		out:   out,
+		start: time.Now(),
+	}
+}
+
+func (tr *Tracer) RoundTrip(req *http.Request) (*http.Response, error) {
+	timing := RequestTiming{
+		Method:   req.Method,
+		Url:      redactUrl(req.URL),
+		Endpoint: req.Method + " " + endpointTemplate(req.URL.Path),
+		Retry:    attemptOf(req.Context()) > 0,
+	}
+
+	// hooks of dialing may be called after RoundTrip returns if another connection served the request
+	var mu sync.Mutex
+	set := func(d *time.Duration, since time.Time) {
+		mu.Lock()
+		*d = time.Since(since)
+		mu.Unlock()
+	}
+
+	var dnsStart, connectStart, tlsStart time.Time
+	start := time.Now()
+	trace := &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone:           func(httptrace.DNSDoneInfo) { set(&timing.DNS, dnsStart) },
+		ConnectStart:      func(string, string) { connectStart = time.Now() },
+		ConnectDone:       func(string, string, error) { set(&timing.Connect, connectStart) },
+		TLSHandshakeStart: func() { tlsStart = time.Now() },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { set(&timing.TLS, tlsStart) },
+		GotFirstResponseByte: func() {
+			set(&timing.TTFB, start)
+		},
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+	res, err := tr.next.RoundTrip(req)
+
+	mu.Lock()
+	defer mu.Unlock()
+	timing.Total = time.Since(start)
+	if err != nil {
+		timing.Err = err
+	} else {
+		timing.Status = res.StatusCode
+		// responses without network access like replayed ones have no first byte event
+		if timing.TTFB == 0 {
+			timing.TTFB = timing.Total
+		}
+	}
+
+	tr.add(timing)
+	return res, err
+}
+
+func (tr *Tracer) add(timing RequestTiming) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	tr.timings = append(tr.timings, timing)
+
+	status := fmt.Sprint(timing.Status)
+	if timing.Err != nil {
+		status = "error"
+	}
+	fmt.Fprintf(tr.out, "trace %s %s %s dns=%v connect=%v tls=%v ttfb=%v total=%v retry=%t\n",
+		timing.Method, timing.Url, status,
+		round(timing.DNS), round(timing.Connect), round(timing.TLS), round(timing.TTFB), round(timing.Total), timing.Retry)
+}
+
+// Timings returns timings of requests in completed order.
+func (tr *Tracer) Timings() []RequestTiming {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	return append([]RequestTiming(nil), tr.timings...)
+}
+
+type endpointSummary struct {
+	endpoint string
+	requests int
+	retries  int
+	errors   int
+	total    time.Duration
+	max      time.Duration
+}
+
+// Summary writes requests by endpoint, the slowest requests and the total time.
+// The sum of request times larger than the elapsed time means requests ran concurrently.
+func (tr *Tracer) Summary(w io.Writer) {
+	timings := tr.Timings()
+	elapsed := time.Since(tr.start)
+
+	byEndpoint := map[string]*endpointSummary{}
+	var total time.Duration
+	retries := 0
+	for _, t := range timings {
+		s, ok := byEndpoint[t.Endpoint]
+		if !ok {
+			s = &endpointSummary{endpoint: t.Endpoint}
+			byEndpoint[t.Endpoint] = s
+		}
+		s.requests++
+		s.total += t.Total
+		if t.Total > s.max {
+			s.max = t.Total
+		}
+		if t.Retry {
+			s.retries++
+			retries++
+		}
+		if t.Err != nil || t.Status >= 400 {
+			s.errors++
+		}
+		total += t.Total
+	}
+
+	summaries := make([]*endpointSummary, 0, len(byEndpoint))
+	for _, s := range byEndpoint {
+		summaries = append(summaries, s)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].total != summaries[j].total {
+			return summaries[i].total > summaries[j].total
+		}
+		return summaries[i].endpoint < summaries[j].endpoint
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ENDPOINT\tREQUESTS\tRETRIES\tERRORS\tTOTAL\tAVG\tMAX")
+	for _, s := range summaries {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%v\t%v\t%v\n",
+			s.endpoint, s.requests, s.retries, s.errors, round(s.total), round(s.total/time.Duration(s.requests)), round(s.max))
+	}
+	tw.Flush()
+
+	slowest := append([]RequestTiming(nil), timings...)
+	sort.SliceStable(slowest, func(i, j int) bool {
+		return slowest[i].Total > slowest[j].Total
+	})
+	if len(slowest) > slowestRequests {
+		slowest = slowest[:slowestRequests]
+	}
+	if len(slowest) > 0 {
+		fmt.Fprintln(w)
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "SLOWEST\tSTATUS\tTTFB\tREQUEST")
+		for _, t := range slowest {
+			fmt.Fprintf(tw, "%v\t%d\t%v\t%s %s\n", round(t.Total), t.Status, round(t.TTFB), t.Method, t.Url)
+		}
+		tw.Flush()
+	}
+
+	fmt.Fprintf(w, "\n%d requests (%d retries) took %v in total, %v elapsed\n", len(timings), retries, round(total), round(elapsed))
+}
+
+// endpointPlaceholders are the placeholders of the path segments following a collection.
+var endpointPlaceholders = map[string][]string{
+	"repositories":        {"{workspace}", "{repository}"},
+	"workspaces":          {"{workspace}"},
+	"projects":            {"{project}"},
+	"repos":               {"{repository}"},
+	"users":               {"{user}"},
+	"groups":              {"{group}"},
+	"default-reviewers":   {"{user}"},
+	"hooks":               {"{hook}"},
+	"branch-restrictions": {"{id}"},
+}
+
+var (
+	apiVersionPattern = regexp.MustCompile(`^[0-9]+\.[0-9]+$`)
+	numericIdPattern  = regexp.MustCompile(`^[0-9]+$`)
+)
+
+// endpointTemplate replaces workspaces, repositories, users, groups and IDs in the path with placeholders,
+// e.g. /repositories/ws/repo/permissions-config/users/{uuid} to /repositories/{workspace}/{repository}/permissions-config/users/{user}.
+// API versions such as 1.0 of /rest/default-reviewers/1.0 are kept.
+func endpointTemplate(p string) string {
+	segments := strings.Split(p, "/")
+	for i := 0; i < len(segments); i++ {
+		switch {
+		case numericIdPattern.MatchString(segments[i]):
+			segments[i] = "{id}"
+		case strings.HasPrefix(segments[i], "{") && strings.HasSuffix(segments[i], "}"):
+			segments[i] = "{uuid}"
+		default:
+			for _, v := range endpointPlaceholders[segments[i]] {
+				if i+1 >= len(segments) || segments[i+1] == "" || apiVersionPattern.MatchString(segments[i+1]) {
+					break
+				}
+				i++
+				segments[i] = v
+			}
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func round(d time.Duration) time.Duration {
+	return d.Round(100 * time.Microsecond)
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracer(t *testing.T) {
+	defer func(d time.Duration) { retryWait = d }(retryWait)
+	retryWait = time.Millisecond
+
+	limited := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/missing":
+			w.WriteHeader(http.StatusNotFound)
+			return
+		case strings.HasSuffix(r.URL.Path, "/users/{uuid-1}") && !limited:
+			limited = true
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, `{}`)
+	}))
+	defer ts.Close()
+
+	var out bytes.Buffer
+	tracer := NewTracer(nil, &out)
+	hc := &http.Client{Transport: tracer}
+	ba := NewBitbucketApi(hc, "user", "pass", WithBaseUrl(ts.URL))
+
+	ctx := context.Background()
+	_, err := ba.do(ctx, "/items?page=1", "GET", nil)
+	assert.NoError(t, err)
+	_, err = ba.do(ctx, "/items?page=2", "GET", nil)
+	assert.NoError(t, err)
+	_, err = ba.do(ctx, "/items?page=2", "GET", nil)
+	assert.NoError(t, err)
+	_, err = ba.do(ctx, "/missing", "GET", nil)
+	assert.Error(t, err)
+	_, err = ba.do(ctx, "/repositories/ws/repo-1/permissions-config/users/{uuid-1}", "PUT", nil)
+	assert.NoError(t, err)
+	_, err = ba.do(ctx, "/repositories/ws/repo-2/permissions-config/users/{uuid-2}", "PUT", nil)
+	assert.NoError(t, err)
+
+	timings := tracer.Timings()
+	assert.Len(t, timings, 7)
+	assert.Equal(t, "GET /items", timings[0].Endpoint)
+	assert.Equal(t, ts.URL+"/items?page=1", timings[0].Url)
+	assert.Equal(t, 200, timings[0].Status)
+	assert.False(t, timings[2].Retry)
+	assert.Equal(t, 404, timings[3].Status)
+	assert.Equal(t, 429, timings[4].Status)
+	assert.False(t, timings[4].Retry)
+	assert.True(t, timings[5].Retry)
+	for _, v := range timings {
+		assert.NotZero(t, v.TTFB)
+		assert.GreaterOrEqual(t, v.Total, v.TTFB)
+	}
+	assert.Contains(t, out.String(), "trace GET "+ts.URL+"/items?page=1 200 ")
+
+	var summary bytes.Buffer
+	tracer.Summary(&summary)
+	assert.Regexp(t, `GET /items\s+3\s+0\s+0\s`, summary.String())
+	assert.Regexp(t, `GET /missing\s+1\s+0\s+1\s`, summary.String())
+	assert.Regexp(t, `PUT /repositories/\{workspace\}/\{repository\}/permissions-config/users/\{user\}\s+3\s+1\s+1\s`, summary.String())
+	assert.Contains(t, summary.String(), "SLOWEST")
+	assert.Contains(t, summary.String(), "7 requests (1 retries)")
+}
+
+func TestEndpointTemplate(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/user", "/user"},
+		{"/2.0/repositories/ws/repo", "/2.0/repositories/{workspace}/{repository}"},
+		{"/repositories/ws/repo/permissions-config/groups", "/repositories/{workspace}/{repository}/permissions-config/groups"},
+		{"/repositories/ws/repo/permissions-config/groups/developers", "/repositories/{workspace}/{repository}/permissions-config/groups/{group}"},
+		{"/repositories/ws/repo/default-reviewers/{1234-abcd}", "/repositories/{workspace}/{repository}/default-reviewers/{user}"},
+		{"/repositories/ws/repo/branch-restrictions/12", "/repositories/{workspace}/{repository}/branch-restrictions/{id}"},
+		{"/repositories/ws/repo/hooks/{hook-1}", "/repositories/{workspace}/{repository}/hooks/{hook}"},
+		{"/rest/api/1.0/projects/PRJ/repos/repo/permissions/users", "/rest/api/1.0/projects/{project}/repos/{repository}/permissions/users"},
+		{"/rest/api/1.0/users/alice", "/rest/api/1.0/users/{user}"},
+		{"/rest/default-reviewers/1.0/projects/PRJ/repos/repo/condition/3", "/rest/default-reviewers/1.0/projects/{project}/repos/{repository}/condition/{id}"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, endpointTemplate(tt.path), tt.path)
+	}
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// gracePeriod is how long the request in flight may continue after the context is canceled.
+const gracePeriod = 30 * time.Second
+
+// UpdateError reports which operations were applied when an update like UpdatePermissions stopped by an error or cancellation.
+type UpdateError[T any] struct {
+	Applied []T
+	// Failed is the operation whose request failed. It is nil if stopped by cancellation before dispatching.
+	Failed  *T
+	Skipped []T
+	Err     error
+}
+
+func (e *UpdateError[T]) Error() string {
+	return fmt.Sprintf("%v: %d applied, %d skipped", e.Err, len(e.Applied), len(e.Skipped))
+}
+
+func (e *UpdateError[T]) Unwrap() error {
+	return e.Err
+}
+
+// Canceled reports whether the update stopped by cancellation or timeout of the context.
+func (e *UpdateError[T]) Canceled() bool {
+	return errors.Is(e.Err, context.Canceled) || errors.Is(e.Err, context.DeadlineExceeded)
+}
+
+// applyOperations applies operations in order. It stops dispatching when ctx is done,
+// while the operation in flight is given gracePeriod to finish so that its result is known.
+func applyOperations[T any](ctx context.Context, operations []T, apply func(context.Context, T) error) error {
+	for i, v := range operations {
+		if err := ctx.Err(); err != nil {
+			return &UpdateError[T]{
+				Applied: operations[:i],
+				Skipped: operations[i:],
+				Err:     err,
+			}
+		}
+
+		reqCtx, cancel := withGracePeriod(ctx, gracePeriod)
+		err := apply(reqCtx, v)
+		cancel()
+		if err != nil {
+			failed := v
+			return &UpdateError[T]{
+				Applied: operations[:i],
+				Failed:  &failed,
+				Skipped: operations[i+1:],
+				Err:     err,
+			}
+		}
+	}
+	return nil
+}
+
+// withGracePeriod returns a context canceled the grace period after ctx is done.
+func withGracePeriod(ctx context.Context, grace time.Duration) (context.Context, context.CancelFunc) {
+	c, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, func() {
+		time.AfterFunc(grace, cancel)
+	})
+	return c, func() {
+		stop()
+		cancel()
+	}
+}
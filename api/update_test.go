@@ -0,0 +1,74 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBitbucketApi_UpdatePermissions_cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	requests := make([]string, 0)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+
+		// interrupted while the first request is in flight
+		cancel()
+		time.Sleep(10 * time.Millisecond)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	ba := NewBitbucketApi(http.DefaultClient, "user", "pass", WithBaseUrl(ts.URL))
+	operations := []Operation{
+		NewRemoveOperation(Permission{ObjectId: "a", ObjectType: ObjectTypeUser}),
+		NewRemoveOperation(Permission{ObjectId: "b", ObjectType: ObjectTypeUser}),
+		NewRemoveOperation(Permission{ObjectId: "c", ObjectType: ObjectTypeGroup}),
+	}
+	err := ba.UpdatePermissions(ctx, "ws", "repo", operations)
+
+	var updateErr *UpdateError[Operation]
+	assert.True(t, errors.As(err, &updateErr))
+	assert.True(t, updateErr.Canceled())
+	assert.Equal(t, operations[:1], updateErr.Applied)
+	assert.Nil(t, updateErr.Failed)
+	assert.Equal(t, operations[1:], updateErr.Skipped)
+	assert.Equal(t, []string{"DELETE /repositories/ws/repo/permissions-config/users/a"}, requests)
+}
+
+func TestBitbucketApi_UpdatePermissions_failure(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/repositories/ws/repo/permissions-config/users/b" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	ba := NewBitbucketApi(http.DefaultClient, "user", "pass", WithBaseUrl(ts.URL))
+	operations := []Operation{
+		NewRemoveOperation(Permission{ObjectId: "a", ObjectType: ObjectTypeUser}),
+		NewRemoveOperation(Permission{ObjectId: "b", ObjectType: ObjectTypeUser}),
+		NewRemoveOperation(Permission{ObjectId: "c", ObjectType: ObjectTypeGroup}),
+	}
+	err := ba.UpdatePermissions(context.Background(), "ws", "repo", operations)
+
+	var updateErr *UpdateError[Operation]
+	assert.True(t, errors.As(err, &updateErr))
+	assert.False(t, updateErr.Canceled())
+	assert.Equal(t, operations[:1], updateErr.Applied)
+	assert.Equal(t, &operations[1], updateErr.Failed)
+	assert.Equal(t, operations[2:], updateErr.Skipped)
+}
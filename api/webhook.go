@@ -0,0 +1,238 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const (
+	endpointWebhooks = "/repositories/%s/%s/hooks"
+	endpointWebhook  = "/repositories/%s/%s/hooks/%s"
+)
+
+// Webhook is a webhook of a repository, identified by its URL between repositories.
+// Secret is only sent to the API, which tells only whether a secret is set.
+type Webhook struct {
+	Uuid        string   `json:"uuid"`
+	Url         string   `json:"url"`
+	Description string   `json:"description"`
+	Active      bool     `json:"active"`
+	Events      []string `json:"events"`
+	SecretSet   bool     `json:"secret_set"`
+	Secret      string   `json:"-"`
+}
+
+// Summary describes the webhook except its URL.
+func (w Webhook) Summary() string {
+	parts := make([]string, 0, 4)
+	if w.Active {
+		parts = append(parts, "active")
+	} else {
+		parts = append(parts, "inactive")
+	}
+	parts = append(parts, "events="+strings.Join(sortedStrings(w.Events), "|"))
+	if w.SecretSet || w.Secret != "" {
+		parts = append(parts, "secret=set")
+	}
+	if w.Description != "" {
+		parts = append(parts, fmt.Sprintf("description=%q", w.Description))
+	}
+	return strings.Join(parts, " ")
+}
+
+func (w Webhook) equal(o Webhook) bool {
+	return w.Url == o.Url &&
+		w.Description == o.Description &&
+		w.Active == o.Active &&
+		w.SecretSet == o.SecretSet &&
+		slices.Equal(sortedStrings(w.Events), sortedStrings(o.Events))
+}
+
+func sortedStrings(s []string) []string {
+	s = slices.Clone(s)
+	sort.Strings(s)
+	return s
+}
+
+// WebhookOperation is an operation to create, update or delete a webhook of the target.
+type WebhookOperation struct {
+	current Webhook
+	after   Webhook
+
+	add    bool
+	remove bool
+	update bool
+}
+
+func NewAddWebhookOperation(w Webhook) WebhookOperation {
+	return WebhookOperation{
+		after: w,
+		add:   true,
+	}
+}
+
+// NewUpdateWebhookOperation updates current to after. It changes nothing if they are equal and no secret is given.
+func NewUpdateWebhookOperation(current, after Webhook) WebhookOperation {
+	return WebhookOperation{
+		current: current,
+		after:   after,
+		update:  !after.equal(current) || after.Secret != "",
+	}
+}
+
+func NewRemoveWebhookOperation(w Webhook) WebhookOperation {
+	return WebhookOperation{
+		current: w,
+		remove:  true,
+	}
+}
+
+// Webhook returns the webhook after the operation, or the removed one.
+func (o WebhookOperation) Webhook() Webhook {
+	if o.remove {
+		return o.current
+	}
+	return o.after
+}
+
+// NeedsSecret reports whether the webhook after the operation has a secret which the target doesn't have,
+// but the secret is not given. Secrets can't be read from the source.
+func (o WebhookOperation) NeedsSecret() bool {
+	if !o.add && !o.update {
+		return false
+	}
+	return o.after.SecretSet && o.after.Secret == "" && (o.add || !o.current.SecretSet)
+}
+
+// WithSecret returns the operation which sets the secret of the webhook.
+func (o WebhookOperation) WithSecret(secret string) WebhookOperation {
+	if o.remove {
+		return o
+	}
+	o.after.Secret = secret
+	o.after.SecretSet = true
+	o.update = !o.add
+	return o
+}
+
+func (o WebhookOperation) Same() bool {
+	return !o.add && !o.update && !o.remove
+}
+
+func (o WebhookOperation) Message() string {
+	switch {
+	case o.update:
+		return fmt.Sprintf("Update: %s (%s) => (%s)", o.after.Url, o.current.Summary(), o.after.Summary())
+	case o.add:
+		return fmt.Sprintf("Add: %s (%s)", o.after.Url, o.after.Summary())
+	case o.remove:
+		return fmt.Sprintf("Remove: %s (%s)", o.current.Url, o.current.Summary())
+	default:
+		return fmt.Sprintf("Same: %s (%s)", o.after.Url, o.after.Summary())
+	}
+}
+
+// MakeWebhookOperationList makes operations to mirror source webhooks to the target.
+// Webhooks are matched by URL.
+func MakeWebhookOperationList(srcWebhooks, targetWebhooks []Webhook) []WebhookOperation {
+	targetMap := map[string]Webhook{}
+	for _, v := range targetWebhooks {
+		targetMap[v.Url] = v
+	}
+	srcMap := map[string]Webhook{}
+	for _, v := range srcWebhooks {
+		srcMap[v.Url] = v
+	}
+
+	result := make([]WebhookOperation, 0)
+	for k, vs := range srcMap {
+		if vt, ok := targetMap[k]; ok {
+			result = append(result, NewUpdateWebhookOperation(vt, vs))
+		} else {
+			result = append(result, NewAddWebhookOperation(vs))
+		}
+	}
+	for k, vt := range targetMap {
+		if _, ok := srcMap[k]; !ok {
+			result = append(result, NewRemoveWebhookOperation(vt))
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Webhook().Url < result[j].Webhook().Url
+	})
+	return result
+}
+
+type bitbucketWebhook struct {
+	Uuid        string   `json:"uuid"`
+	Url         string   `json:"url"`
+	Description string   `json:"description"`
+	Active      bool     `json:"active"`
+	Events      []string `json:"events"`
+	SecretSet   bool     `json:"secret_set"`
+}
+
+func (v bitbucketWebhook) webhook() Webhook {
+	return Webhook{
+		Uuid:        v.Uuid,
+		Url:         v.Url,
+		Description: v.Description,
+		Active:      v.Active,
+		Events:      sortedStrings(v.Events),
+		SecretSet:   v.SecretSet,
+	}
+}
+
+// newWebhookRequest returns the body to create or update a webhook.
+// The secret is kept unless it is given, and removed by null if the webhook has no secret any more.
+func newWebhookRequest(current, after Webhook) map[string]any {
+	body := map[string]any{
+		"url":         after.Url,
+		"description": after.Description,
+		"active":      after.Active,
+		"events":      sortedStrings(after.Events),
+	}
+	switch {
+	case after.Secret != "":
+		body["secret"] = after.Secret
+	case !after.SecretSet && current.SecretSet:
+		body["secret"] = nil
+	}
+	return body
+}
+
+// ListWebhooks gets webhooks of a repository.
+func (ba *BitbucketApi) ListWebhooks(ctx context.Context, workspace, repository string) ([]Webhook, error) {
+	return listAll(ctx, ba, fmt.Sprintf(endpointWebhooks, workspace, repository), ba.pageOptions, bitbucketWebhook.webhook)
+}
+
+// UpdateWebhooks creates, updates and deletes webhooks of a repository according to operations.
+// It stops at the first failure or cancellation of ctx with *UpdateError[WebhookOperation].
+func (ba *BitbucketApi) UpdateWebhooks(ctx context.Context, workspace, repository string, operations []WebhookOperation) error {
+	return applyOperations(ctx, operations, func(ctx context.Context, v WebhookOperation) error {
+		switch {
+		case v.add, v.update:
+			body, err := json.Marshal(newWebhookRequest(v.current, v.after))
+			if err != nil {
+				return err
+			}
+			if v.add {
+				_, err = ba.do(ctx, fmt.Sprintf(endpointWebhooks, workspace, repository), "POST", bytes.NewBuffer(body))
+			} else {
+				_, err = ba.do(ctx, fmt.Sprintf(endpointWebhook, workspace, repository, v.current.Uuid), "PUT", bytes.NewBuffer(body))
+			}
+			return err
+
+		case v.remove:
+			_, err := ba.do(ctx, fmt.Sprintf(endpointWebhook, workspace, repository, v.current.Uuid), "DELETE", nil)
+			return err
+		}
+		return nil
+	})
+}
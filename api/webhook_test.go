@@ -0,0 +1,93 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeWebhookOperationList(t *testing.T) {
+	src := []Webhook{
+		{Uuid: "{1}", Url: "https://ci.example.com/hook", Active: true, Events: []string{"repo:push", "pullrequest:created"}, SecretSet: true},
+		{Uuid: "{2}", Url: "https://chat.example.com/hook", Active: true, Events: []string{"repo:push"}},
+		{Uuid: "{3}", Url: "https://new.example.com/hook", Active: false, Events: []string{"repo:push"}, SecretSet: true},
+	}
+	target := []Webhook{
+		{Uuid: "{11}", Url: "https://ci.example.com/hook", Active: true, Events: []string{"pullrequest:created", "repo:push"}, SecretSet: true},
+		{Uuid: "{12}", Url: "https://chat.example.com/hook", Active: false, Events: []string{"repo:push"}},
+		{Uuid: "{13}", Url: "https://old.example.com/hook", Active: true, Events: []string{"repo:push"}},
+	}
+
+	got := MakeWebhookOperationList(src, target)
+	messages := make([]string, 0)
+	for _, v := range got {
+		messages = append(messages, v.Message())
+	}
+	assert.Equal(t, []string{
+		"Update: https://chat.example.com/hook (inactive events=repo:push) => (active events=repo:push)",
+		"Same: https://ci.example.com/hook (active events=pullrequest:created|repo:push secret=set)",
+		"Add: https://new.example.com/hook (inactive events=repo:push secret=set)",
+		"Remove: https://old.example.com/hook (active events=repo:push)",
+	}, messages)
+
+	assert.False(t, got[0].NeedsSecret())
+	assert.False(t, got[1].NeedsSecret())
+	assert.True(t, got[2].NeedsSecret())
+	assert.False(t, got[2].WithSecret("s3cret").NeedsSecret())
+	assert.True(t, got[1].WithSecret("s3cret").Message() != got[1].Message())
+	assert.True(t, got[3].WithSecret("s3cret").Message() == got[3].Message())
+}
+
+func TestBitbucketApi_UpdateWebhooks(t *testing.T) {
+	requests := make([]string, 0)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		requests = append(requests, fmt.Sprintf("%s %s %s", r.Method, r.URL.Path, b))
+		if r.Method == "DELETE" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		fmt.Fprint(w, `{}`)
+	}))
+	defer ts.Close()
+
+	ba := NewBitbucketApi(http.DefaultClient, "user", "pass", WithBaseUrl(ts.URL))
+	operations := []WebhookOperation{
+		NewAddWebhookOperation(Webhook{Url: "https://ci.example.com/hook", Active: true, Events: []string{"repo:push"}, SecretSet: true, Secret: "s3cret"}),
+		NewUpdateWebhookOperation(
+			Webhook{Uuid: "{12}", Url: "https://chat.example.com/hook", Events: []string{"repo:push"}, SecretSet: true},
+			Webhook{Uuid: "{2}", Url: "https://chat.example.com/hook", Active: true, Events: []string{"repo:push", "issue:created"}},
+		),
+		NewRemoveWebhookOperation(Webhook{Uuid: "{13}", Url: "https://old.example.com/hook"}),
+	}
+	err := ba.UpdateWebhooks(context.Background(), "ws", "repo", operations)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{
+		`POST /repositories/ws/repo/hooks {"active":true,"description":"","events":["repo:push"],"secret":"s3cret","url":"https://ci.example.com/hook"}`,
+		`PUT /repositories/ws/repo/hooks/{12} {"active":true,"description":"","events":["issue:created","repo:push"],"secret":null,"url":"https://chat.example.com/hook"}`,
+		`DELETE /repositories/ws/repo/hooks/{13} `,
+	}, requests)
+}
+
+func TestBitbucketApi_ListWebhooks(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/repositories/ws/repo/hooks", r.URL.Path)
+		fmt.Fprint(w, `{"values": [
+			{"uuid": "{1}", "url": "https://ci.example.com/hook", "description": "CI", "active": true, "events": ["repo:push", "pullrequest:created"], "secret_set": true}
+		]}`)
+	}))
+	defer ts.Close()
+
+	ba := NewBitbucketApi(http.DefaultClient, "user", "pass", WithBaseUrl(ts.URL))
+	got, err := ba.ListWebhooks(context.Background(), "ws", "repo")
+	assert.NoError(t, err)
+	assert.Equal(t, []Webhook{
+		{Uuid: "{1}", Url: "https://ci.example.com/hook", Description: "CI", Active: true, Events: []string{"pullrequest:created", "repo:push"}, SecretSet: true},
+	}, got)
+}
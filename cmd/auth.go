@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// scopeRepositoryAdmin is required by permission commands.
+const scopeRepositoryAdmin = "repository:admin"
+
+// authCmd represents the auth command
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Show authentication",
+}
+
+// authStatusCmd represents the auth status command
+var authStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the authenticated account and its scopes",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ba, err := newBackend()
+		if err != nil {
+			return err
+		}
+
+		p := profile
+		if p == "" {
+			p = "(default)"
+		}
+		fmt.Printf("Profile: %s\n", p)
+		fmt.Printf("Auth type: %s\n", currentAuthType())
+
+		user, err := ba.GetCurrentUser(cmd.Context())
+		if err != nil {
+			fmt.Printf("Failed to authenticate: %v\n", err)
+			return err
+		}
+
+		fmt.Printf("Account: %s (%s) %s\n", user.Nickname, user.DisplayName, user.Uuid)
+		if user.Scopes == nil {
+			fmt.Println("Scopes: unknown")
+			fmt.Printf("%s: unknown (not reported by Bitbucket)\n", scopeRepositoryAdmin)
+			return nil
+		}
+
+		fmt.Printf("Scopes: %s\n", strings.Join(user.Scopes, ", "))
+		if !user.HasScope(scopeRepositoryAdmin) {
+			fmt.Printf("%s: no\n", scopeRepositoryAdmin)
+			return fmt.Errorf("%s scope is required for permission commands", scopeRepositoryAdmin)
+		}
+		fmt.Printf("%s: yes\n", scopeRepositoryAdmin)
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(authCmd)
+	authCmd.AddCommand(authStatusCmd)
+}
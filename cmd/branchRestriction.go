@@ -0,0 +1,353 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/ikorihn/bbdan/api"
+	"github.com/spf13/cobra"
+)
+
+// branchRestrictionCmd represents the branch-restriction command
+var branchRestrictionCmd = &cobra.Command{
+	Use:   "branch-restriction",
+	Short: "List, copy, apply, remove branch restrictions of repository",
+}
+
+// listBranchRestrictionCmd represents the branch-restriction list command
+var listBranchRestrictionCmd = &cobra.Command{
+	Use:   "list workspace repository",
+	Short: "List branch restrictions of a repository",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		workspace := args[0]
+		repository := args[1]
+		output, _ := cmd.Flags().GetString("output")
+
+		br, err := newBranchRestrictionBackend()
+		if err != nil {
+			return err
+		}
+
+		restrictions, err := br.ListBranchRestrictions(cmd.Context(), workspace, repository)
+		if err != nil {
+			return err
+		}
+
+		switch output {
+		case "json":
+			return writeBranchRestrictionSpecs(os.Stdout, restrictions)
+		case "", "text":
+			fmt.Printf("List branch restrictions for %s/%s\n", workspace, repository)
+			printBranchRestrictions(restrictions)
+			return nil
+		default:
+			return fmt.Errorf("invalid output %q: must be text or json", output)
+		}
+	},
+}
+
+// copyBranchRestrictionCmd represents the branch-restriction copy command
+var copyBranchRestrictionCmd = &cobra.Command{
+	Use:   "copy workspace source-repository target-repository",
+	Short: "Copy branch restrictions between repositories",
+	Long:  "Make branch restrictions of the target repository the same as the source. Restrictions are matched by kind and branches",
+	Args:  cobra.ExactArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		workspace := args[0]
+		srcRepository := args[1]
+		targetRepository := args[2]
+		batch, _ := cmd.Flags().GetBool("batch")
+
+		fmt.Printf("Copy branch restrictions from %s/%s to %s/%s\n", workspace, srcRepository, workspace, targetRepository)
+
+		br, err := newBranchRestrictionBackend()
+		if err != nil {
+			return err
+		}
+
+		ctx := cmd.Context()
+
+		srcRestrictions, err := br.ListBranchRestrictions(ctx, workspace, srcRepository)
+		if err != nil {
+			return err
+		}
+		return applyBranchRestrictions(cmd, br, workspace, targetRepository, srcRestrictions, batch)
+	},
+}
+
+// applyBranchRestrictionCmd represents the branch-restriction apply command
+var applyBranchRestrictionCmd = &cobra.Command{
+	Use:   "apply workspace repository...",
+	Short: "Apply branch restrictions of a JSON file to repositories",
+	Long: `Make branch restrictions of repositories the same as a JSON file, which is written by list --output json.
+
+[
+  {"kind": "push", "pattern": "main", "users": ["{user-uuid}"], "groups": ["administrators"]},
+  {"kind": "require_approvals_to_merge", "branch_type": "production", "value": 2}
+]`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		workspace := args[0]
+		repositories := args[1:]
+		file, _ := cmd.Flags().GetString("file")
+		batch, _ := cmd.Flags().GetBool("batch")
+
+		restrictions, err := readBranchRestrictionSpecs(file)
+		if err != nil {
+			return err
+		}
+
+		br, err := newBranchRestrictionBackend()
+		if err != nil {
+			return err
+		}
+
+		for _, repository := range repositories {
+			fmt.Printf("Apply branch restrictions of %s to %s/%s\n", file, workspace, repository)
+			if err := applyBranchRestrictions(cmd, br, workspace, repository, restrictions, batch); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+// removeBranchRestrictionCmd represents the branch-restriction remove command
+var removeBranchRestrictionCmd = &cobra.Command{
+	Use:   "remove workspace repository",
+	Short: "Remove branch restrictions of a repository",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		workspace := args[0]
+		repository := args[1]
+		kind, _ := cmd.Flags().GetString("kind")
+		pattern, _ := cmd.Flags().GetString("pattern")
+		yes, _ := cmd.Flags().GetBool("yes")
+
+		if kind != "" {
+			if _, err := api.ParseBranchRestrictionKind(kind); err != nil {
+				return err
+			}
+		}
+
+		fmt.Printf("Remove branch restrictions of %s/%s\n", workspace, repository)
+
+		br, err := newBranchRestrictionBackend()
+		if err != nil {
+			return err
+		}
+
+		ctx := cmd.Context()
+
+		restrictions, err := br.ListBranchRestrictions(ctx, workspace, repository)
+		if err != nil {
+			return err
+		}
+
+		operations := make([]api.BranchRestrictionOperation, 0)
+		for _, v := range restrictions {
+			if kind != "" && string(v.Kind) != kind {
+				continue
+			}
+			if pattern != "" {
+				if ok, _ := path.Match(pattern, v.Branch()); !ok {
+					continue
+				}
+			}
+			operations = append(operations, api.NewRemoveBranchRestrictionOperation(v))
+		}
+		if len(operations) == 0 {
+			return errors.New("no branch restrictions to remove")
+		}
+
+		selectedOperations, err := selectOperations(operations, yes)
+		if err != nil {
+			return err
+		}
+
+		err = br.UpdateBranchRestrictions(ctx, workspace, repository, selectedOperations)
+		if err != nil {
+			printUpdateError[api.BranchRestrictionOperation](err)
+			return err
+		}
+
+		return showBranchRestrictions(cmd, br, workspace, repository)
+	},
+}
+
+// newBranchRestrictionBackend returns the backend if it supports branch restrictions.
+func newBranchRestrictionBackend() (api.BranchRestrictionBackend, error) {
+	ba, err := newBackend()
+	if err != nil {
+		return nil, err
+	}
+	br, ok := ba.(api.BranchRestrictionBackend)
+	if !ok {
+		return nil, errors.New("branch restrictions are supported only by Bitbucket Cloud")
+	}
+	return br, nil
+}
+
+// applyBranchRestrictions makes branch restrictions of the repository the same as restrictions.
+func applyBranchRestrictions(cmd *cobra.Command, br api.BranchRestrictionBackend, workspace, repository string, restrictions []api.BranchRestriction, batch bool) error {
+	ctx := cmd.Context()
+
+	targetRestrictions, err := br.ListBranchRestrictions(ctx, workspace, repository)
+	if err != nil {
+		return err
+	}
+
+	operations := api.MakeBranchRestrictionOperationList(restrictions, targetRestrictions)
+	selectedOperations, err := selectOperations(operations, batch)
+	if err != nil {
+		return err
+	}
+
+	err = br.UpdateBranchRestrictions(ctx, workspace, repository, selectedOperations)
+	if err != nil {
+		printUpdateError[api.BranchRestrictionOperation](err)
+		return err
+	}
+
+	return showBranchRestrictions(cmd, br, workspace, repository)
+}
+
+func showBranchRestrictions(cmd *cobra.Command, br api.BranchRestrictionBackend, workspace, repository string) error {
+	restrictions, err := br.ListBranchRestrictions(cmd.Context(), workspace, repository)
+	if err != nil {
+		return err
+	}
+	printBranchRestrictions(restrictions)
+	return nil
+}
+
+func printBranchRestrictions(restrictions []api.BranchRestriction) {
+	fmt.Println("==== RESULT ====")
+	fmt.Println("id, kind, branch, value, users, groups")
+	for _, v := range restrictions {
+		users := make([]string, 0, len(v.Users))
+		for _, u := range v.Users {
+			users = append(users, u.Nickname)
+		}
+		fmt.Printf("%d, %s, %s, %d, %s, %s\n",
+			v.Id,
+			v.Kind,
+			v.Branch(),
+			v.Value,
+			strings.Join(users, "|"),
+			strings.Join(v.Groups, "|"),
+		)
+	}
+}
+
+// branchRestrictionSpec is a branch restriction in files of apply.
+// Branches are selected by branch_type of the branching model if set, otherwise by pattern.
+type branchRestrictionSpec struct {
+	Kind       string   `json:"kind"`
+	Pattern    string   `json:"pattern,omitempty"`
+	BranchType string   `json:"branch_type,omitempty"`
+	Value      int      `json:"value,omitempty"`
+	Users      []string `json:"users,omitempty"`
+	Groups     []string `json:"groups,omitempty"`
+}
+
+func (s branchRestrictionSpec) restriction() (api.BranchRestriction, error) {
+	kind, err := api.ParseBranchRestrictionKind(s.Kind)
+	if err != nil {
+		return api.BranchRestriction{}, err
+	}
+
+	r := api.BranchRestriction{
+		Kind:            kind,
+		BranchMatchKind: api.BranchMatchGlob,
+		Pattern:         s.Pattern,
+		Value:           s.Value,
+		Users:           make([]api.Account, 0),
+		Groups:          append([]string{}, s.Groups...),
+	}
+	switch {
+	case s.BranchType != "":
+		r.BranchMatchKind = api.BranchMatchBranchingModel
+		r.BranchType = s.BranchType
+		r.Pattern = ""
+	case s.Pattern == "":
+		return api.BranchRestriction{}, fmt.Errorf("%s: pattern or branch_type is required", s.Kind)
+	}
+	for _, v := range s.Users {
+		r.Users = append(r.Users, api.Account{Uuid: v})
+	}
+	return r, nil
+}
+
+func readBranchRestrictionSpecs(file string) ([]api.BranchRestriction, error) {
+	if file == "" {
+		return nil, errors.New("--file is required")
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var specs []branchRestrictionSpec
+	if err := json.Unmarshal(b, &specs); err != nil {
+		return nil, fmt.Errorf("invalid branch restrictions %s: %w", file, err)
+	}
+
+	restrictions := make([]api.BranchRestriction, 0, len(specs))
+	for _, v := range specs {
+		r, err := v.restriction()
+		if err != nil {
+			return nil, fmt.Errorf("invalid branch restrictions %s: %w", file, err)
+		}
+		restrictions = append(restrictions, r)
+	}
+	return restrictions, nil
+}
+
+func writeBranchRestrictionSpecs(w io.Writer, restrictions []api.BranchRestriction) error {
+	specs := make([]branchRestrictionSpec, 0, len(restrictions))
+	for _, v := range restrictions {
+		s := branchRestrictionSpec{
+			Kind:       string(v.Kind),
+			Pattern:    v.Pattern,
+			BranchType: v.BranchType,
+			Value:      v.Value,
+			Groups:     v.Groups,
+		}
+		if v.BranchMatchKind == api.BranchMatchBranchingModel {
+			s.Pattern = ""
+		} else {
+			s.BranchType = ""
+		}
+		for _, u := range v.Users {
+			s.Users = append(s.Users, u.Uuid)
+		}
+		specs = append(specs, s)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(specs)
+}
+
+func init() {
+	rootCmd.AddCommand(branchRestrictionCmd)
+	branchRestrictionCmd.AddCommand(listBranchRestrictionCmd)
+	branchRestrictionCmd.AddCommand(copyBranchRestrictionCmd)
+	branchRestrictionCmd.AddCommand(applyBranchRestrictionCmd)
+	branchRestrictionCmd.AddCommand(removeBranchRestrictionCmd)
+
+	listBranchRestrictionCmd.Flags().StringP("output", "o", "text", "Output format: text|json. json can be used by apply")
+	copyBranchRestrictionCmd.Flags().BoolP("batch", "b", false, "Execute in batch mode. Copy all without asking")
+	applyBranchRestrictionCmd.Flags().StringP("file", "f", "", "JSON file of branch restrictions")
+	applyBranchRestrictionCmd.Flags().BoolP("batch", "b", false, "Execute in batch mode. Apply all without asking")
+	removeBranchRestrictionCmd.Flags().String("kind", "", "Remove only restrictions of the kind, e.g. push")
+	removeBranchRestrictionCmd.Flags().String("pattern", "", "Remove only restrictions of branches matching the shell pattern, e.g. release/*")
+	removeBranchRestrictionCmd.Flags().BoolP("yes", "y", false, "Remove all matching restrictions without asking")
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ikorihn/bbdan/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func newBranchRestrictionFakeBackend() *fakeBackend {
+	fb := newFakeBackend()
+	fb.branchRestrictions["myworkspace/src"] = []api.BranchRestriction{
+		{Id: 1, Kind: api.BranchRestrictionPush, BranchMatchKind: api.BranchMatchGlob, Pattern: "main", Groups: []string{"administrators"}},
+		{Id: 2, Kind: api.BranchRestrictionRequireApprovalsToMerge, BranchMatchKind: api.BranchMatchGlob, Pattern: "main", Value: 2},
+	}
+	fb.branchRestrictions["myworkspace/target"] = []api.BranchRestriction{
+		{Id: 11, Kind: api.BranchRestrictionRequireApprovalsToMerge, BranchMatchKind: api.BranchMatchGlob, Pattern: "main", Value: 1},
+		{Id: 12, Kind: api.BranchRestrictionForce, BranchMatchKind: api.BranchMatchGlob, Pattern: "release/*"},
+		{Id: 13, Kind: api.BranchRestrictionDelete, BranchMatchKind: api.BranchMatchBranchingModel, BranchType: "release"},
+	}
+	return fb
+}
+
+func TestBranchRestrictionCopyCmd(t *testing.T) {
+	fb := newBranchRestrictionFakeBackend()
+	err := executeCommand(t, fb, "branch-restriction", "copy", "-b", "myworkspace", "src", "target")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"myworkspace/target": {
+			"Remove: delete type:release ()",
+			"Remove: force release/* ()",
+			"Add: push main (groups=administrators)",
+			"Update: require_approvals_to_merge main (value=1) => (value=2)",
+		},
+	}, fb.branchOperations)
+}
+
+func TestBranchRestrictionApplyCmd(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "restrictions.json")
+	err := os.WriteFile(file, []byte(`[
+		{"kind": "require_approvals_to_merge", "pattern": "main", "value": 1},
+		{"kind": "delete", "branch_type": "release"}
+	]`), 0o600)
+	assert.NoError(t, err)
+
+	fb := newBranchRestrictionFakeBackend()
+	err = executeCommand(t, fb, "branch-restriction", "apply", "-b", "-f", file, "myworkspace", "target")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"myworkspace/target": {
+			"Remove: force release/* ()",
+		},
+	}, fb.branchOperations)
+
+	err = os.WriteFile(file, []byte(`[{"kind": "unknown", "pattern": "main"}]`), 0o600)
+	assert.NoError(t, err)
+	err = executeCommand(t, fb, "branch-restriction", "apply", "-b", "-f", file, "myworkspace", "target")
+	assert.Error(t, err)
+}
+
+func TestBranchRestrictionRemoveCmd(t *testing.T) {
+	fb := newBranchRestrictionFakeBackend()
+	err := executeCommand(t, fb, "branch-restriction", "remove", "--pattern", "release/*", "--yes", "myworkspace", "target")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"myworkspace/target": {
+			"Remove: force release/* ()",
+		},
+	}, fb.branchOperations)
+}
@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ikorihn/bbdan/api"
+	"github.com/spf13/cobra"
+)
+
+// branchingModelCmd represents the branching-model command
+var branchingModelCmd = &cobra.Command{
+	Use:   "branching-model",
+	Short: "Show, copy, apply branching model settings of repository",
+}
+
+// showBranchingModelCmd represents the branching-model show command
+var showBranchingModelCmd = &cobra.Command{
+	Use:   "show workspace repository",
+	Short: "Show branching model settings of a repository",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		workspace := args[0]
+		repository := args[1]
+		output, _ := cmd.Flags().GetString("output")
+
+		bm, err := newBranchingModelBackend()
+		if err != nil {
+			return err
+		}
+
+		model, err := bm.GetBranchingModel(cmd.Context(), workspace, repository)
+		if err != nil {
+			return err
+		}
+
+		switch output {
+		case "json":
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(model)
+		case "", "text":
+			fmt.Printf("Show branching model for %s/%s\n", workspace, repository)
+			printSettings(model.Settings())
+			return nil
+		default:
+			return fmt.Errorf("invalid output %q: must be text or json", output)
+		}
+	},
+}
+
+// copyBranchingModelCmd represents the branching-model copy command
+var copyBranchingModelCmd = &cobra.Command{
+	Use:   "copy workspace source-repository target-repository",
+	Short: "Copy branching model settings between repositories",
+	Long:  "Make branching model settings of the target repository the same as the source, showing the difference of each field before applying",
+	Args:  cobra.ExactArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		workspace := args[0]
+		srcRepository := args[1]
+		targetRepository := args[2]
+		batch, _ := cmd.Flags().GetBool("batch")
+
+		fmt.Printf("Copy branching model from %s/%s to %s/%s\n", workspace, srcRepository, workspace, targetRepository)
+
+		bm, err := newBranchingModelBackend()
+		if err != nil {
+			return err
+		}
+
+		model, err := bm.GetBranchingModel(cmd.Context(), workspace, srcRepository)
+		if err != nil {
+			return err
+		}
+		return applyBranchingModel(cmd, bm, workspace, targetRepository, model, batch)
+	},
+}
+
+// applyBranchingModelCmd represents the branching-model apply command
+var applyBranchingModelCmd = &cobra.Command{
+	Use:   "apply workspace repository...",
+	Short: "Apply branching model settings of a JSON file to repositories",
+	Long: `Make branching model settings of repositories the same as a JSON file, which is written by show --output json.
+
+{
+  "development": {"name": "develop", "use_mainbranch": false},
+  "production": {"name": "", "use_mainbranch": true, "enabled": true},
+  "branch_types": [
+    {"kind": "feature", "enabled": true, "prefix": "feature/"},
+    {"kind": "hotfix", "enabled": false}
+  ]
+}`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		workspace := args[0]
+		repositories := args[1:]
+		file, _ := cmd.Flags().GetString("file")
+		batch, _ := cmd.Flags().GetBool("batch")
+
+		model, err := readBranchingModel(file)
+		if err != nil {
+			return err
+		}
+
+		bm, err := newBranchingModelBackend()
+		if err != nil {
+			return err
+		}
+
+		for _, repository := range repositories {
+			fmt.Printf("Apply branching model of %s to %s/%s\n", file, workspace, repository)
+			if err := applyBranchingModel(cmd, bm, workspace, repository, model, batch); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+// newBranchingModelBackend returns the backend if it supports branching model settings.
+func newBranchingModelBackend() (api.BranchingModelBackend, error) {
+	ba, err := newBackend()
+	if err != nil {
+		return nil, err
+	}
+	bm, ok := ba.(api.BranchingModelBackend)
+	if !ok {
+		return nil, errors.New("branching model settings are supported only by Bitbucket Cloud")
+	}
+	return bm, nil
+}
+
+// applyBranchingModel changes the selected fields of branching model settings of the repository to model.
+func applyBranchingModel(cmd *cobra.Command, bm api.BranchingModelBackend, workspace, repository string, model api.BranchingModel, batch bool) error {
+	ctx := cmd.Context()
+
+	current, err := bm.GetBranchingModel(ctx, workspace, repository)
+	if err != nil {
+		return err
+	}
+
+	changes := api.MakeSettingChanges(model.Settings(), current.Settings())
+	printSettingChanges(changes)
+	selectedChanges, err := selectOperations(changes, batch)
+	if err != nil {
+		return err
+	}
+	if len(selectedChanges) > 0 {
+		if err := bm.UpdateBranchingModel(ctx, workspace, repository, selectedChanges); err != nil {
+			fmt.Printf("Failed to update: %v\n", err)
+			return err
+		}
+	}
+
+	result, err := bm.GetBranchingModel(ctx, workspace, repository)
+	if err != nil {
+		return err
+	}
+	printSettings(result.Settings())
+	return nil
+}
+
+func readBranchingModel(file string) (api.BranchingModel, error) {
+	if file == "" {
+		return api.BranchingModel{}, errors.New("--file is required")
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return api.BranchingModel{}, err
+	}
+
+	var model api.BranchingModel
+	if err := json.Unmarshal(b, &model); err != nil {
+		return api.BranchingModel{}, fmt.Errorf("invalid branching model %s: %w", file, err)
+	}
+	for _, v := range model.BranchTypes {
+		if v.Kind == "" {
+			return api.BranchingModel{}, fmt.Errorf("invalid branching model %s: kind of branch_types is required", file)
+		}
+	}
+	return model, nil
+}
+
+func init() {
+	rootCmd.AddCommand(branchingModelCmd)
+	branchingModelCmd.AddCommand(showBranchingModelCmd)
+	branchingModelCmd.AddCommand(copyBranchingModelCmd)
+	branchingModelCmd.AddCommand(applyBranchingModelCmd)
+
+	showBranchingModelCmd.Flags().StringP("output", "o", "text", "Output format: text|json. json can be used by apply")
+	copyBranchingModelCmd.Flags().BoolP("batch", "b", false, "Execute in batch mode. Copy all without asking")
+	applyBranchingModelCmd.Flags().StringP("file", "f", "", "JSON file of branching model settings")
+	applyBranchingModelCmd.Flags().BoolP("batch", "b", false, "Execute in batch mode. Apply all without asking")
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ikorihn/bbdan/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func newBranchingModelFakeBackend() *fakeBackend {
+	fb := newFakeBackend()
+	fb.branchingModels["myworkspace/src"] = api.BranchingModel{
+		Development: api.BranchingModelBranch{Name: "develop"},
+		Production:  api.BranchingModelBranch{UseMainBranch: true, Enabled: true},
+		BranchTypes: []api.BranchType{{Kind: "feature", Enabled: true, Prefix: "feature/"}},
+	}
+	fb.branchingModels["myworkspace/target"] = api.BranchingModel{
+		Development: api.BranchingModelBranch{UseMainBranch: true},
+		Production:  api.BranchingModelBranch{UseMainBranch: true, Enabled: true},
+		BranchTypes: []api.BranchType{{Kind: "feature", Enabled: true, Prefix: "feat/"}},
+	}
+	return fb
+}
+
+func TestBranchingModelCopyCmd(t *testing.T) {
+	fb := newBranchingModelFakeBackend()
+	err := executeCommand(t, fb, "branching-model", "copy", "-b", "myworkspace", "src", "target")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"myworkspace/target": {
+			"Update: development.use_mainbranch: true => false",
+			`Update: development.branch: "" => develop`,
+			"Update: branch_types.feature.prefix: feat/ => feature/",
+		},
+	}, fb.settingChanges)
+}
+
+func TestBranchingModelApplyCmd(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "branching-model.json")
+	err := os.WriteFile(file, []byte(`{
+		"development": {"name": "", "use_mainbranch": true},
+		"production": {"name": "", "use_mainbranch": true, "enabled": true},
+		"branch_types": [{"kind": "feature", "enabled": false}]
+	}`), 0o600)
+	assert.NoError(t, err)
+
+	fb := newBranchingModelFakeBackend()
+	err = executeCommand(t, fb, "branching-model", "apply", "-b", "-f", file, "myworkspace", "src", "target")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"myworkspace/src": {
+			"Update: development.use_mainbranch: false => true",
+			"Update: branch_types.feature.enabled: true => false",
+		},
+		"myworkspace/target": {
+			"Update: branch_types.feature.enabled: true => false",
+		},
+	}, fb.settingChanges)
+
+	err = os.WriteFile(file, []byte(`{"branch_types": [{"enabled": true}]}`), 0o600)
+	assert.NoError(t, err)
+	err = executeCommand(t, fb, "branching-model", "apply", "-b", "-f", file, "myworkspace", "target")
+	assert.Error(t, err)
+}
@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/ikorihn/bbdan/api"
+	"github.com/spf13/cobra"
+)
+
+// bulkCmd represents the bulk command
+var bulkCmd = &cobra.Command{
+	Use:   "bulk",
+	Short: "Apply permission changes from CSV or JSON lines",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, _ := cmd.Flags().GetString("file")
+		format, _ := cmd.Flags().GetString("format")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		changes, err := readChanges(file, format)
+		if err != nil {
+			return err
+		}
+
+		ba, err := newBackend()
+		if err != nil {
+			return err
+		}
+
+		ctx := cmd.Context()
+
+		// group changes by repository keeping the order of appearance
+		repositories := make([]string, 0)
+		changesByRepository := map[string][]api.Change{}
+		for _, c := range changes {
+			key := c.Workspace + "/" + c.Repository
+			if _, ok := changesByRepository[key]; !ok {
+				repositories = append(repositories, key)
+			}
+			changesByRepository[key] = append(changesByRepository[key], c)
+		}
+
+		results := map[int]string{}
+		failed := 0
+		skipped := 0
+		for _, key := range repositories {
+			repoChanges := changesByRepository[key]
+			workspace := repoChanges[0].Workspace
+			repository := repoChanges[0].Repository
+
+			// stop dispatching after interrupted or timed out, and report the rest as skipped
+			if err := ctx.Err(); err != nil {
+				for _, c := range repoChanges {
+					results[c.Line] = fmt.Sprintf("skipped: %v", err)
+				}
+				skipped += len(repoChanges)
+				continue
+			}
+			fmt.Printf("Apply %d changes to %s\n", len(repoChanges), key)
+
+			permissions, err := ba.ListPermission(ctx, workspace, repository)
+			if err != nil {
+				for _, c := range repoChanges {
+					results[c.Line] = fmt.Sprintf("failed: %v", err)
+				}
+				failed += len(repoChanges)
+				continue
+			}
+
+			for _, c := range repoChanges {
+				o := c.Operation(permissions)
+				if o.Same() {
+					results[c.Line] = "unchanged"
+					continue
+				}
+				if dryRun {
+					results[c.Line] = "planned: " + o.Message()
+					permissions = api.ApplyOperation(permissions, o)
+					continue
+				}
+				if err := ctx.Err(); err != nil {
+					results[c.Line] = fmt.Sprintf("skipped: %s: %v", o.Message(), err)
+					skipped++
+					continue
+				}
+
+				err := ba.UpdatePermissions(ctx, workspace, repository, []api.Operation{o})
+				// report the error of the operation without the summary of UpdateError, which is always of this single operation
+				var updateErr *api.UpdateError[api.Operation]
+				if errors.As(err, &updateErr) {
+					err = updateErr.Err
+				}
+				if err != nil {
+					results[c.Line] = fmt.Sprintf("failed: %s: %v", o.Message(), err)
+					failed++
+					continue
+				}
+				results[c.Line] = "applied: " + o.Message()
+				permissions = api.ApplyOperation(permissions, o)
+			}
+		}
+
+		fmt.Println("==== RESULT ====")
+		fmt.Println("line, workspace, repository, type, principal, result")
+		for _, c := range changes {
+			fmt.Printf("%d, %s, %s, %s, %s, %s\n",
+				c.Line,
+				c.Workspace,
+				c.Repository,
+				c.ObjectType,
+				c.Principal,
+				results[c.Line],
+			)
+		}
+
+		if failed > 0 || skipped > 0 {
+			return fmt.Errorf("%d of %d changes failed, %d skipped", failed, len(changes), skipped)
+		}
+		return nil
+	},
+}
+
+// readChanges reads changes from the file, or stdin if the file is empty or "-".
+// The format is guessed from the extension unless specified. Stdin defaults to JSON lines.
+func readChanges(file, format string) ([]api.Change, error) {
+	var r io.Reader = os.Stdin
+	if file != "" && file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+
+		if format == "" && filepath.Ext(file) == ".csv" {
+			format = "csv"
+		}
+	}
+
+	switch format {
+	case "csv":
+		return api.ReadChangesCSV(r)
+	case "", "jsonl", "json":
+		return api.ReadChangesJSONLines(r)
+	default:
+		return nil, fmt.Errorf("invalid format %q: must be csv or jsonl", format)
+	}
+}
+
+func init() {
+	permissionCmd.AddCommand(bulkCmd)
+	bulkCmd.Flags().StringP("file", "f", "", "CSV or JSON lines file. Reads stdin if empty or -")
+	bulkCmd.Flags().String("format", "", "Input format: csv|jsonl. Guessed from the file extension by default")
+	bulkCmd.Flags().Bool("dry-run", false, "Show planned operations without applying them")
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ikorihn/bbdan/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBulkCmd(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "changes.csv")
+	err := os.WriteFile(file, []byte("workspace,repository,type,principal,permission\nmyworkspace,myrepository,group,developer,write\nmyworkspace,other-repository,group,developer,read\n"), 0o600)
+	assert.NoError(t, err)
+
+	fb := newFakeBackend()
+	err = executeCommand(t, fb, "permission", "bulk", "-f", file)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"myworkspace/myrepository":     {"Add: group developer (WRITE)"},
+		"myworkspace/other-repository": {"Add: group developer (READ)"},
+	}, fb.operations)
+}
+
+func TestBulkCmd_sameUserByIdAndName(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "changes.csv")
+	err := os.WriteFile(file, []byte("myworkspace,myrepository,user,{1111},write\nmyworkspace,myrepository,user,john-doe,admin\n"), 0o600)
+	assert.NoError(t, err)
+
+	fb := newFakeBackend()
+	fb.permissions["myworkspace/myrepository"] = []api.Permission{
+		{ObjectId: "{1111}", ObjectName: "john-doe", ObjectType: api.ObjectTypeUser, PermissionType: api.PermissionTypeAdmin},
+	}
+	err = executeCommand(t, fb, "permission", "bulk", "-f", file)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"myworkspace/myrepository": {
+			"Update: user john-doe ADMIN => WRITE",
+			"Update: user john-doe WRITE => ADMIN",
+		},
+	}, fb.operations)
+}
+
+func TestBulkCmd_duplicate(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "changes.csv")
+	err := os.WriteFile(file, []byte("myworkspace,myrepository,group,developer,write\nmyworkspace,myrepository,group,developer,read\n"), 0o600)
+	assert.NoError(t, err)
+
+	fb := newFakeBackend()
+	err = executeCommand(t, fb, "permission", "bulk", "-f", file)
+	assert.EqualError(t, err, "line 2: duplicate change of group developer on myworkspace/myrepository, first at line 1")
+	assert.Empty(t, fb.operations)
+}
+
+func TestBulkCmd_timeout(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "changes.csv")
+	err := os.WriteFile(file, []byte("myworkspace,myrepository,group,developer,write\n"), 0o600)
+	assert.NoError(t, err)
+	t.Cleanup(func() { timeout = 0 })
+
+	fb := newFakeBackend()
+	err = executeCommand(t, fb, "--timeout", "1ns", "permission", "bulk", "-f", file)
+	assert.EqualError(t, err, "0 of 1 changes failed, 1 skipped")
+	assert.Empty(t, fb.operations)
+}
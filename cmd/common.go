@@ -2,19 +2,50 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/ikorihn/bbdan/api"
+	"github.com/spf13/cobra"
 )
 
-func showPermissions(ba *api.BitbucketApi, workspace, repository string) {
-	permissions, err := ba.ListPermission(context.Background(), workspace, repository)
+func showPermissions(ctx context.Context, ba api.Backend, workspace, repository string) {
+	permissions, err := ba.ListPermission(ctx, workspace, repository)
 	if err != nil {
 		fmt.Printf("%v", err)
 		return
 	}
 
+	printPermissions(permissions)
+}
+
+// printUpdateError prints the error of an update like UpdatePermissions with operations applied and skipped before it stopped.
+func printUpdateError[T change](err error) {
+	fmt.Printf("Failed to update: %v\n", err)
+
+	var updateErr *api.UpdateError[T]
+	if !errors.As(err, &updateErr) {
+		return
+	}
+	if updateErr.Canceled() {
+		fmt.Println("Interrupted. Operations not yet started were skipped")
+	}
+	fmt.Println("==== APPLIED ====")
+	for _, v := range updateErr.Applied {
+		fmt.Println(v.Message())
+	}
+	if updateErr.Failed != nil {
+		fmt.Println("==== FAILED ====")
+		fmt.Println((*updateErr.Failed).Message())
+	}
+	fmt.Println("==== SKIPPED ====")
+	for _, v := range updateErr.Skipped {
+		fmt.Println(v.Message())
+	}
+}
+
+func printPermissions(permissions []api.Permission) {
 	fmt.Println("==== RESULT ====")
 	fmt.Println("type, id, name, permission")
 	for _, v := range permissions {
@@ -25,11 +56,46 @@ func showPermissions(ba *api.BitbucketApi, workspace, repository string) {
 			v.PermissionType,
 		)
 	}
+}
+
+// addFilterFlags adds flags to filter permissions by object.
+func addFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().String("only", "", "Only target objects of the type: users|groups")
+	cmd.Flags().StringSlice("include", nil, "Only target objects whose id or name matches the glob patterns")
+	cmd.Flags().StringSlice("exclude", nil, "Do not target objects whose id or name matches the glob patterns")
+}
+
+// getFilter builds filter from flags added by addFilterFlags.
+func getFilter(cmd *cobra.Command) (api.Filter, error) {
+	only, _ := cmd.Flags().GetString("only")
+	include, _ := cmd.Flags().GetStringSlice("include")
+	exclude, _ := cmd.Flags().GetStringSlice("exclude")
+
+	f := api.Filter{
+		Include: include,
+		Exclude: exclude,
+	}
+	switch only {
+	case "":
+	case "users", "user":
+		f.ObjectType = api.ObjectTypeUser
+	case "groups", "group":
+		f.ObjectType = api.ObjectTypeGroup
+	default:
+		return api.Filter{}, fmt.Errorf("invalid --only %q: must be users or groups", only)
+	}
 
+	return f, nil
 }
 
-func askOperation(operations []api.Operation) ([]api.Operation, error) {
-	notSame := make([]api.Operation, 0)
+// change is an operation chosen by askOperation.
+type change interface {
+	Same() bool
+	Message() string
+}
+
+func askOperation[T change](operations []T) ([]T, error) {
+	notSame := make([]T, 0)
 	for _, v := range operations {
 		if !v.Same() {
 			notSame = append(notSame, v)
@@ -45,7 +111,7 @@ func askOperation(operations []api.Operation) ([]api.Operation, error) {
 		return nil, err
 	}
 
-	selectedOperations := make([]api.Operation, 0)
+	selectedOperations := make([]T, 0)
 	for _, v := range selectedIdx {
 		selectedOperations = append(selectedOperations, notSame[v])
 	}
@@ -53,6 +119,129 @@ func askOperation(operations []api.Operation) ([]api.Operation, error) {
 	return selectedOperations, nil
 }
 
+// selectOperations returns operations that change permissions.
+// In batch mode all of them are selected, otherwise the user chooses.
+func selectOperations[T change](operations []T, batch bool) ([]T, error) {
+	if !batch {
+		return askOperation(operations)
+	}
+
+	selected := make([]T, 0)
+	for _, v := range operations {
+		if !v.Same() {
+			selected = append(selected, v)
+		}
+	}
+	return selected, nil
+}
+
+// addPrincipalFlags adds flags to specify objects without prompts.
+func addPrincipalFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSlice("user", nil, "User UUID or account id. Nickname matches only users who already have permission")
+	cmd.Flags().StringSlice("group", nil, "Group slug or name")
+}
+
+// getPrincipals returns objects specified by flags added by addPrincipalFlags.
+// ObjectId and ObjectName of them are the value of the flag.
+func getPrincipals(cmd *cobra.Command) []api.Permission {
+	users, _ := cmd.Flags().GetStringSlice("user")
+	groups, _ := cmd.Flags().GetStringSlice("group")
+
+	principals := make([]api.Permission, 0)
+	for _, v := range users {
+		principals = append(principals, api.Permission{ObjectId: v, ObjectName: v, ObjectType: api.ObjectTypeUser})
+	}
+	for _, v := range groups {
+		principals = append(principals, api.Permission{ObjectId: v, ObjectName: v, ObjectType: api.ObjectTypeGroup})
+	}
+	return principals
+}
+
+// findPermission finds the permission of the principal by id or name.
+func findPermission(permissions []api.Permission, principal api.Permission) (api.Permission, bool) {
+	return api.FindPermission(permissions, principal.ObjectType, principal.ObjectId)
+}
+
+// resolvePrincipal finds the permission of the principal among all permissions of the repository.
+// ok is false if the principal has no permission, and an error is returned if the permission is excluded by filter.
+func resolvePrincipal(permissions []api.Permission, filter api.Filter, principal api.Permission) (api.Permission, bool, error) {
+	current, ok := findPermission(permissions, principal)
+	if !ok {
+		return api.Permission{}, false, nil
+	}
+	if !filter.Match(current) {
+		return api.Permission{}, true, fmt.Errorf("%s %s is excluded by filter", principal.ObjectType, principal.ObjectId)
+	}
+	return current, true, nil
+}
+
+// grantOperations makes operations to give the permission to principals specified by flags.
+// Principals without permission are added and the others are updated, skipping those which already have it.
+// permissions are all permissions of the repository, and principals excluded by filter are rejected.
+func grantOperations(permissions []api.Permission, filter api.Filter, principals []api.Permission, permission api.PermissionType) ([]api.Operation, error) {
+	operations := []api.Operation{}
+	for _, v := range principals {
+		current, ok, err := resolvePrincipal(permissions, filter, v)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case !ok:
+			v.PermissionType = permission
+			operations = append(operations, api.NewAddOperation(v))
+		case current.PermissionType != permission:
+			operations = append(operations, api.NewUpdateOperation(current, permission))
+		default:
+			fmt.Printf("Skip: %s %s already has %s\n", current.ObjectType, current.ObjectName, permission)
+		}
+	}
+	return operations, nil
+}
+
+func askConfirm(message string) (bool, error) {
+	prompt := &survey.Confirm{
+		Message: message,
+	}
+
+	confirmed := false
+	err := survey.AskOne(prompt, &confirmed)
+	if err != nil {
+		return false, err
+	}
+
+	return confirmed, nil
+}
+
+func askInput(message, defaultValue string) (string, error) {
+	prompt := &survey.Input{
+		Message: message,
+		Default: defaultValue,
+	}
+
+	var answer string
+	err := survey.AskOne(prompt, &answer)
+	if err != nil {
+		return "", err
+	}
+
+	return answer, nil
+}
+
+// askPassword asks a secret without echoing it.
+func askPassword(message string) (string, error) {
+	prompt := &survey.Password{
+		Message: message,
+	}
+
+	var answer string
+	err := survey.AskOne(prompt, &answer, survey.WithValidator(survey.Required))
+	if err != nil {
+		return "", err
+	}
+
+	return answer, nil
+}
+
 func askPermissionToUpdate(permissions []api.Permission) ([]api.Permission, error) {
 	messages := make([]string, len(permissions))
 	for i, v := range permissions {
@@ -124,3 +313,20 @@ func multiSelect(message string, options []string) ([]int, error) {
 
 	return selectedIdx, nil
 }
+
+func printSettingChanges(changes []api.SettingChange) {
+	fmt.Println("==== DIFF ====")
+	for _, v := range changes {
+		if !v.Same() {
+			fmt.Println(v.Message())
+		}
+	}
+}
+
+func printSettings(settings []api.Setting) {
+	fmt.Println("==== RESULT ====")
+	fmt.Println("field, value")
+	for _, v := range settings {
+		fmt.Printf("%s, %s\n", v.Field, v.Value)
+	}
+}
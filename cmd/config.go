@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/ikorihn/bbdan/api"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage config file",
+}
+
+// configInitCmd represents the config init command
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create config file interactively",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := configFilePath()
+		fmt.Printf("Configure %s\n", path)
+
+		name, err := askInput("Profile name (empty for default):", profile)
+		if err != nil {
+			return err
+		}
+
+		var flavor string
+		err = survey.AskOne(&survey.Select{
+			Message: "Choose Bitbucket product:",
+			Options: []string{flavorCloud, flavorDataCenter},
+		}, &flavor)
+		if err != nil {
+			return err
+		}
+		values := map[string]string{
+			"flavor": flavor,
+		}
+		if flavor == flavorDataCenter {
+			if values["base_url"], err = askInput("Base URL (e.g. https://bitbucket.example.com):", ""); err != nil {
+				return err
+			}
+		}
+
+		var authType string
+		err = survey.AskOne(&survey.Select{
+			Message: "Choose authentication type:",
+			Options: []string{authTypeBasic, authTypeBearer, authTypeOAuth2},
+		}, &authType)
+		if err != nil {
+			return err
+		}
+
+		// network settings such as proxy and ca_bundle are of the current profile
+		hc, err := newHTTPClient()
+		if err != nil {
+			return err
+		}
+
+		values["auth_type"] = authType
+		var auth api.Authenticator
+		switch authType {
+		case authTypeBasic:
+			if values["username"], err = askInput("Username:", ""); err != nil {
+				return err
+			}
+			if values["password"], err = askPassword("App password:"); err != nil {
+				return err
+			}
+			auth = api.BasicAuth{Username: values["username"], Password: values["password"]}
+		case authTypeBearer:
+			if values["token"], err = askPassword("Access token:"); err != nil {
+				return err
+			}
+			auth = api.BearerToken{Token: values["token"]}
+		case authTypeOAuth2:
+			if values["client_id"], err = askInput("OAuth consumer key:", ""); err != nil {
+				return err
+			}
+			if values["client_secret"], err = askPassword("OAuth consumer secret:"); err != nil {
+				return err
+			}
+			auth = api.NewOAuth2ClientCredentials(hc, values["client_id"], values["client_secret"])
+		}
+
+		var ba api.Backend = api.NewBitbucketApiWithAuth(hc, auth)
+		if flavor == flavorDataCenter {
+			ba = api.NewDataCenterApi(hc, values["base_url"], auth)
+		}
+		if err := validateCredentials(cmd.Context(), ba); err != nil {
+			fmt.Printf("Validation failed: %v\n", err)
+			save, err := askConfirm("Save anyway?")
+			if err != nil {
+				return err
+			}
+			if !save {
+				return nil
+			}
+		}
+
+		prefix := ""
+		if name != "" {
+			prefix = fmt.Sprintf("profiles.%s.", name)
+		}
+		for k, v := range values {
+			viper.Set(prefix+k, v)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return err
+		}
+		viper.SetConfigPermissions(0o600)
+		if err := viper.WriteConfigAs(path); err != nil {
+			return err
+		}
+		// WriteConfigAs does not change permissions of an existing file
+		if err := os.Chmod(path, 0o600); err != nil {
+			return err
+		}
+
+		fmt.Printf("Saved to %s\n", path)
+		return nil
+	},
+}
+
+// validateCredentials checks that credentials can get the account and permissions of a repository.
+func validateCredentials(ctx context.Context, ba api.Backend) error {
+	user, err := ba.GetCurrentUser(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Authenticated as %s (%s)\n", user.Nickname, user.DisplayName)
+
+	repository, err := askInput("Repository to check permission access (workspace/repository or project/repository, empty to skip):", "")
+	if err != nil {
+		return err
+	}
+	if repository == "" {
+		return nil
+	}
+	workspace, slug, ok := strings.Cut(repository, "/")
+	if !ok {
+		return fmt.Errorf("invalid repository %q: must be workspace/repository", repository)
+	}
+	if _, err := ba.ListPermission(ctx, workspace, slug); err != nil {
+		return fmt.Errorf("cannot read permissions of %s. Make sure the credentials have %s: %w", repository, scopeRepositoryAdmin, err)
+	}
+	fmt.Printf("Permissions of %s are accessible\n", repository)
+
+	return nil
+}
+
+// configFilePath returns the path of the config file in use,
+// or the default location if no config file exists.
+func configFilePath() string {
+	if f := viper.ConfigFileUsed(); f != "" {
+		return f
+	}
+
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, _ := os.UserHomeDir()
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "bbdan", "config.toml")
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configInitCmd)
+}
@@ -1,9 +1,7 @@
 package cmd
 
 import (
-	"context"
 	"fmt"
-	"net/http"
 
 	"github.com/ikorihn/bbdan/api"
 	"github.com/spf13/cobra"
@@ -18,12 +16,25 @@ var copyCmd = &cobra.Command{
 		workspace := args[0]
 		srcRepository := args[1]
 		targetRepository := args[2]
+
+		s, _ := cmd.Flags().GetString("strategy")
+		strategy, err := api.ParseStrategy(s)
+		if err != nil {
+			return err
+		}
+		filter, err := getFilter(cmd)
+		if err != nil {
+			return err
+		}
+
 		fmt.Printf("Copy permissions from %s/%s to %s/%s\n", workspace, srcRepository, workspace, targetRepository)
 
-		hc := http.DefaultClient
-		ba := api.NewBitbucketApi(hc, username, password)
+		ba, err := newBackend()
+		if err != nil {
+			return err
+		}
 
-		ctx := context.Background()
+		ctx := cmd.Context()
 
 		srcPermissions, err := ba.ListPermission(ctx, workspace, srcRepository)
 		if err != nil {
@@ -34,30 +45,22 @@ var copyCmd = &cobra.Command{
 			return err
 		}
 
-		operations := api.MakeOperationList(srcPermissions, targetPermissions)
+		operations := api.MakeOperationListWithStrategy(srcPermissions, targetPermissions, strategy)
+		operations = api.FilterOperations(operations, filter)
 
-		var selectedOperations []api.Operation
 		batch, _ := cmd.Flags().GetBool("batch")
-		if batch {
-			for _, v := range operations {
-				if !v.Same() {
-					selectedOperations = append(selectedOperations, v)
-				}
-			}
-		} else {
-			selectedOperations, err = askOperation(operations)
-			if err != nil {
-				return err
-			}
+		selectedOperations, err := selectOperations(operations, batch)
+		if err != nil {
+			return err
 		}
 
 		err = ba.UpdatePermissions(ctx, workspace, targetRepository, selectedOperations)
 		if err != nil {
-			fmt.Printf("Failed to update: %v\n", err)
+			printUpdateError[api.Operation](err)
 			return err
 		}
 
-		showPermissions(ba, workspace, targetRepository)
+		showPermissions(ctx, ba, workspace, targetRepository)
 		return nil
 	},
 }
@@ -65,4 +68,6 @@ var copyCmd = &cobra.Command{
 func init() {
 	permissionCmd.AddCommand(copyCmd)
 	copyCmd.PersistentFlags().BoolP("batch", "b", false, "Execute in batch mode. Copy all without asking")
+	addFilterFlags(copyCmd)
+	copyCmd.PersistentFlags().StringP("strategy", "s", string(api.StrategyMirror), "How to merge permissions: mirror|additive|max|min")
 }
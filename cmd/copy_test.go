@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ikorihn/bbdan/api"
+	"github.com/ikorihn/bbdan/mockserver"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCopyCmd(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "mirror",
+			args: []string{"permission", "copy", "-b", "myworkspace", "src", "target"},
+			want: []string{
+				"Update: group developer READ => WRITE",
+				"Add: user john-doe (ADMIN)",
+				"Remove: user bot-ci (WRITE)",
+			},
+		},
+		{
+			name: "additive excluding bots",
+			args: []string{"permission", "copy", "-b", "-s", "additive", "--exclude", "bot-*", "myworkspace", "src", "target"},
+			want: []string{
+				"Update: group developer READ => WRITE",
+				"Add: user john-doe (ADMIN)",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fb := newFakeBackend()
+			fb.permissions["myworkspace/src"] = []api.Permission{
+				{ObjectId: "developer", ObjectName: "developer", ObjectType: api.ObjectTypeGroup, PermissionType: api.PermissionTypeWrite},
+				{ObjectId: "{1111}", ObjectName: "john-doe", ObjectType: api.ObjectTypeUser, PermissionType: api.PermissionTypeAdmin},
+			}
+			fb.permissions["myworkspace/target"] = []api.Permission{
+				{ObjectId: "developer", ObjectName: "developer", ObjectType: api.ObjectTypeGroup, PermissionType: api.PermissionTypeRead},
+				{ObjectId: "{2222}", ObjectName: "bot-ci", ObjectType: api.ObjectTypeUser, PermissionType: api.PermissionTypeWrite},
+			}
+
+			err := executeCommand(t, fb, tt.args...)
+			assert.NoError(t, err)
+			assert.Equal(t, map[string][]string{"myworkspace/target": tt.want}, fb.operations)
+		})
+	}
+}
+
+func TestCopyCmd_outsideChange(t *testing.T) {
+	fixture, err := mockserver.LoadFixture("../mockserver/testdata/fixture.json")
+	assert.NoError(t, err)
+	ts := httptest.NewServer(mockserver.New(fixture, 0))
+	defer ts.Close()
+	t.Setenv("BBDAN_USERNAME", "user")
+	t.Setenv("BBDAN_PASSWORD", "pass")
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	copyArgs := []string{"permission", "copy", "-b", "myworkspace", "myrepository", "other-repository"}
+	err = executeCommandWithOptions(t, "", copyArgs, WithHTTPClient(http.DefaultClient), WithBaseUrl(ts.URL))
+	assert.NoError(t, err)
+
+	// another user removes a permission after the copy listed the target
+	ctx := context.Background()
+	ba := api.NewBitbucketApi(http.DefaultClient, "user", "pass", api.WithBaseUrl(ts.URL))
+	want, err := ba.ListPermission(ctx, "myworkspace", "myrepository")
+	assert.NoError(t, err)
+	err = ba.UpdatePermissions(ctx, "myworkspace", "other-repository", []api.Operation{api.NewRemoveOperation(want[0])})
+	assert.NoError(t, err)
+
+	err = executeCommandWithOptions(t, "", copyArgs, WithHTTPClient(http.DefaultClient), WithBaseUrl(ts.URL))
+	assert.NoError(t, err)
+	got, err := ba.ListPermission(ctx, "myworkspace", "other-repository")
+	assert.NoError(t, err)
+	assert.Equal(t, want, got)
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// credentialHost is the host of Bitbucket Cloud given to the credential helper, which is the host of git remotes.
+const credentialHost = "bitbucket.org"
+
+// credentialTarget returns the protocol and host given to the credential helper.
+// They are those of baseUrl for Bitbucket Data Center, and of bitbucket.org if baseUrl is empty.
+func credentialTarget(baseUrl string) (string, string, error) {
+	if baseUrl == "" {
+		return "https", credentialHost, nil
+	}
+	u, err := url.Parse(baseUrl)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid base_url: %w", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", "", fmt.Errorf("invalid base_url %q: scheme and host are required", baseUrl)
+	}
+	return u.Scheme, u.Host, nil
+}
+
+// runPasswordCommand runs the command with shell and returns the first line of stdout as the password.
+// The output is never included in errors.
+func runPasswordCommand(command string) (string, error) {
+	var stdout bytes.Buffer
+	c := exec.Command("sh", "-c", command)
+	c.Stdin = os.Stdin
+	c.Stdout = &stdout
+	c.Stderr = os.Stderr
+	if err := c.Run(); err != nil {
+		return "", fmt.Errorf("password_command failed: %w", err)
+	}
+
+	line, _, _ := strings.Cut(stdout.String(), "\n")
+	line = strings.TrimRight(line, "\r")
+	if line == "" {
+		return "", errors.New("password_command printed no password")
+	}
+	return line, nil
+}
+
+// runCredentialHelper gets credentials with the git credential helper protocol.
+// helper is resolved as git does: "!cmd" runs cmd with shell, an absolute path runs it,
+// and other names run git-credential-<name>.
+// Credentials are asked for the host of baseUrl, or bitbucket.org if it is empty.
+// It returns the username from the helper if username is empty.
+func runCredentialHelper(helper, baseUrl, username string) (string, string, error) {
+	protocol, host, err := credentialTarget(baseUrl)
+	if err != nil {
+		return "", "", err
+	}
+
+	var command string
+	switch {
+	case strings.HasPrefix(helper, "!"):
+		command = strings.TrimPrefix(helper, "!") + " get"
+	case filepath.IsAbs(helper):
+		command = helper + " get"
+	default:
+		command = "git credential-" + helper + " get"
+	}
+
+	var stdin bytes.Buffer
+	fmt.Fprintf(&stdin, "protocol=%s\nhost=%s\n", protocol, host)
+	if username != "" {
+		fmt.Fprintf(&stdin, "username=%s\n", username)
+	}
+	stdin.WriteString("\n")
+
+	var stdout bytes.Buffer
+	c := exec.Command("sh", "-c", command)
+	c.Stdin = &stdin
+	c.Stdout = &stdout
+	c.Stderr = os.Stderr
+	if err := c.Run(); err != nil {
+		return "", "", fmt.Errorf("credential_helper failed: %w", err)
+	}
+
+	var password string
+	scanner := bufio.NewScanner(&stdout)
+	for scanner.Scan() {
+		k, v, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "username":
+			if username == "" {
+				username = v
+			}
+		case "password":
+			password = v
+		}
+	}
+	if password == "" {
+		return "", "", fmt.Errorf("credential_helper returned no password for %s", host)
+	}
+
+	return username, password, nil
+}
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ikorihn/bbdan/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunPasswordCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "first line",
+			command: `printf 'secret\nsecond\n'`,
+			want:    "secret",
+		},
+		{
+			name:    "crlf",
+			command: `printf 'secret\r\n'`,
+			want:    "secret",
+		},
+		{
+			name:    "no output",
+			command: "true",
+			wantErr: "password_command printed no password",
+		},
+		{
+			name:    "failure",
+			command: "echo secret; exit 3",
+			wantErr: "password_command failed: exit status 3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := runPasswordCommand(tt.command)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRunCredentialHelper(t *testing.T) {
+	dir := t.TempDir()
+	// the helper echoes the request, so the username is returned only if it is given
+	helper := filepath.Join(dir, "helper")
+	err := os.WriteFile(helper, []byte("#!/bin/sh\ntest \"$1\" = get || exit 1\ngrep '^username=' || true\necho password=secret\necho ignored\n"), 0o700)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		helper       string
+		baseUrl      string
+		username     string
+		wantUsername string
+		wantPassword string
+		wantErr      string
+	}{
+		{
+			name:         "absolute path",
+			helper:       helper,
+			username:     "john",
+			wantUsername: "john",
+			wantPassword: "secret",
+		},
+		{
+			name:         "username from helper",
+			helper:       `!printf 'username=jane\npassword=pass\n'; true`,
+			wantUsername: "jane",
+			wantPassword: "pass",
+		},
+		{
+			name:         "username given is kept",
+			helper:       `!printf 'username=jane\npassword=pass\n'; true`,
+			username:     "john",
+			wantUsername: "john",
+			wantPassword: "pass",
+		},
+		{
+			name:     "no password",
+			helper:   `!printf 'username=jane\n'; true`,
+			username: "john",
+			wantErr:  "credential_helper returned no password for bitbucket.org",
+		},
+		{
+			name:         "data center",
+			helper:       `!tr '\n' ' ' | grep -q 'protocol=http host=bitbucket.example.com:7990 ' && echo password=dc; true`,
+			baseUrl:      "http://bitbucket.example.com:7990/bitbucket",
+			wantPassword: "dc",
+		},
+		{
+			name:    "data center without password",
+			helper:  "!true",
+			baseUrl: "https://bitbucket.example.com",
+			wantErr: "credential_helper returned no password for bitbucket.example.com",
+		},
+		{
+			name:    "failure",
+			helper:  "!exit 2; true",
+			wantErr: "credential_helper failed: exit status 2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotUsername, gotPassword, err := runCredentialHelper(tt.helper, tt.baseUrl, tt.username)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantUsername, gotUsername)
+			assert.Equal(t, tt.wantPassword, gotPassword)
+		})
+	}
+}
+
+func TestResolvePassword_lazy(t *testing.T) {
+	t.Setenv("BBDAN_PASSWORD", "")
+	os.Unsetenv("BBDAN_PASSWORD")
+	marker := filepath.Join(t.TempDir(), "called")
+	config := `username = "john"
+password_command = "touch ` + marker + `; echo secret"
+`
+
+	err := executeCommandWithConfig(t, newFakeBackend(), config, "version")
+	assert.NoError(t, err)
+	assert.NoFileExists(t, marker)
+
+	auth, err := newAuthenticator(http.DefaultClient)
+	assert.NoError(t, err)
+	assert.Equal(t, api.BasicAuth{Username: "john", Password: "secret"}, auth)
+	assert.FileExists(t, marker)
+}
+
+func TestResolvePassword_credentialHelperDataCenter(t *testing.T) {
+	t.Setenv("BBDAN_PASSWORD", "")
+	os.Unsetenv("BBDAN_PASSWORD")
+	config := `username = "john"
+base_url = "https://bitbucket.example.com"
+credential_helper = "!grep -q '^host=bitbucket.example.com$' && echo password=dc-secret; true"
+`
+
+	err := executeCommandWithConfig(t, newFakeBackend(), config, "version")
+	assert.NoError(t, err)
+
+	auth, err := newAuthenticator(http.DefaultClient)
+	assert.NoError(t, err)
+	assert.Equal(t, api.BasicAuth{Username: "john", Password: "dc-secret"}, auth)
+}
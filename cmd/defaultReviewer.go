@@ -1,9 +1,7 @@
 package cmd
 
 import (
-	"context"
 	"fmt"
-	"net/http"
 	"strings"
 
 	"github.com/ikorihn/bbdan/api"
@@ -26,10 +24,11 @@ var listDefaultReviewerCmd = &cobra.Command{
 		repository := args[1]
 		fmt.Printf("List default reviewers for %s/%s\n", workspace, repository)
 
-		hc := http.DefaultClient
-
-		ba := api.NewBitbucketApi(hc, username, password)
-		ctx := context.Background()
+		ba, err := newBackend()
+		if err != nil {
+			return err
+		}
+		ctx := cmd.Context()
 		accounts, err := ba.ListDefaultReviewers(ctx, workspace, repository)
 		if err != nil {
 			fmt.Printf("%v", err)
@@ -61,10 +60,11 @@ var overwriteDefaultReviewerCmd = &cobra.Command{
 
 		fmt.Printf("Overwrite default reviewers of %s/%s\n", workspace, repository)
 
-		hc := http.DefaultClient
-
-		ba := api.NewBitbucketApi(hc, username, password)
-		ctx := context.Background()
+		ba, err := newBackend()
+		if err != nil {
+			return err
+		}
+		ctx := cmd.Context()
 		currentReviewers, err := ba.ListDefaultReviewers(ctx, workspace, repository)
 		if err != nil {
 			fmt.Printf("%v", err)
@@ -75,8 +75,16 @@ var overwriteDefaultReviewerCmd = &cobra.Command{
 			curReviewerIds = append(curReviewerIds, v.Uuid)
 		}
 
-		ba.DeleteDefaultReviewers(ctx, workspace, repository, curReviewerIds)
-		ba.AddDefaultReviewers(ctx, workspace, repository, reviewers)
+		_, err = ba.DeleteDefaultReviewers(ctx, workspace, repository, curReviewerIds)
+		if err != nil {
+			printUpdateError[api.DefaultReviewerOperation](err)
+			return err
+		}
+		_, err = ba.AddDefaultReviewers(ctx, workspace, repository, reviewers)
+		if err != nil {
+			printUpdateError[api.DefaultReviewerOperation](err)
+			return err
+		}
 
 		accounts, err := ba.ListDefaultReviewers(ctx, workspace, repository)
 		if err != nil {
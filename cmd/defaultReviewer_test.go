@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ikorihn/bbdan/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOverwriteDefaultReviewerCmd(t *testing.T) {
+	fb := newFakeBackend()
+	fb.reviewers["myworkspace/myrepository"] = []api.Account{
+		{Uuid: "{1111}", Nickname: "john-doe"},
+		{Uuid: "{2222}", Nickname: "reader-1"},
+	}
+
+	err := executeCommand(t, fb, "default-reviewer", "overwrite", "myworkspace", "myrepository", "{3333},{4444}")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"myworkspace/myrepository": {"{1111}", "{2222}"}}, fb.deletedReviewers)
+	assert.Equal(t, map[string][]string{"myworkspace/myrepository": {"{3333}", "{4444}"}}, fb.addedReviewers)
+}
+
+func TestOverwriteDefaultReviewerCmd_error(t *testing.T) {
+	fb := newFakeBackend()
+	fb.reviewers["myworkspace/myrepository"] = []api.Account{
+		{Uuid: "{1111}", Nickname: "john-doe"},
+	}
+	fb.reviewerErr = errors.New("http request error: 403 Forbidden")
+
+	err := executeCommand(t, fb, "default-reviewer", "overwrite", "myworkspace", "myrepository", "{3333}")
+	assert.EqualError(t, err, "http request error: 403 Forbidden")
+	assert.Empty(t, fb.addedReviewers)
+}
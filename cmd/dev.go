@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/ikorihn/bbdan/mockserver"
+	"github.com/spf13/cobra"
+)
+
+// devCmd represents the dev command
+var devCmd = &cobra.Command{
+	Use:   "dev",
+	Short: "Tools for development and rehearsal",
+}
+
+// mockServerCmd represents the dev mock-server command
+var mockServerCmd = &cobra.Command{
+	Use:   "mock-server",
+	Short: "Serve an in-memory imitation of Bitbucket Cloud API",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fixtureFile, _ := cmd.Flags().GetString("fixture")
+		addr, _ := cmd.Flags().GetString("addr")
+		pagelen, _ := cmd.Flags().GetInt("pagelen")
+
+		var fixture mockserver.Fixture
+		if fixtureFile != "" {
+			var err error
+			fixture, err = mockserver.LoadFixture(fixtureFile)
+			if err != nil {
+				return err
+			}
+		}
+
+		fmt.Printf("Listening on http://%s\n", addr)
+		fmt.Printf("Use it with flavor = \"cloud\" and base_url = \"http://%s\" in a profile\n", addr)
+
+		return http.ListenAndServe(addr, mockserver.New(fixture, pagelen))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(devCmd)
+	devCmd.AddCommand(mockServerCmd)
+	mockServerCmd.Flags().StringP("fixture", "f", "", "JSON file of users, groups and repositories to serve")
+	mockServerCmd.Flags().String("addr", "127.0.0.1:8080", "Address to listen on")
+	mockServerCmd.Flags().Int("pagelen", mockserver.DefaultPagelen, "Default page size of list endpoints")
+}
@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ikorihn/bbdan/api"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// fakeBackend is an in-memory api.Backend recording changes.
+type fakeBackend struct {
+	permissions        map[string][]api.Permission
+	reviewers          map[string][]api.Account
+	branchRestrictions map[string][]api.BranchRestriction
+	branchingModels    map[string]api.BranchingModel
+	repositorySettings map[string]api.RepositorySettings
+	webhooks           map[string][]api.Webhook
+
+	// operations is messages of operations applied, by workspace/repository
+	operations map[string][]string
+	// branchOperations is messages of branch restriction operations applied, by workspace/repository
+	branchOperations map[string][]string
+	// settingChanges is messages of setting changes applied, by workspace/repository
+	settingChanges map[string][]string
+	// webhookOperations is messages of webhook operations applied, by workspace/repository
+	webhookOperations map[string][]string
+	addedReviewers    map[string][]string
+	deletedReviewers  map[string][]string
+	// reviewerErr is returned by AddDefaultReviewers and DeleteDefaultReviewers if not nil
+	reviewerErr error
+}
+
+func newFakeBackend() *fakeBackend {
+	return &fakeBackend{
+		permissions:        map[string][]api.Permission{},
+		reviewers:          map[string][]api.Account{},
+		branchRestrictions: map[string][]api.BranchRestriction{},
+		branchingModels:    map[string]api.BranchingModel{},
+		repositorySettings: map[string]api.RepositorySettings{},
+		webhooks:           map[string][]api.Webhook{},
+		operations:         map[string][]string{},
+		branchOperations:   map[string][]string{},
+		settingChanges:     map[string][]string{},
+		webhookOperations:  map[string][]string{},
+		addedReviewers:     map[string][]string{},
+		deletedReviewers:   map[string][]string{},
+	}
+}
+
+func (f *fakeBackend) GetCurrentUser(ctx context.Context) (api.CurrentUser, error) {
+	return api.CurrentUser{Account: api.Account{Uuid: "{me}", Nickname: "me"}}, nil
+}
+
+func (f *fakeBackend) ListPermission(ctx context.Context, workspace, repository string) ([]api.Permission, error) {
+	return f.permissions[workspace+"/"+repository], nil
+}
+
+func (f *fakeBackend) UpdatePermissions(ctx context.Context, workspace, repository string, operations []api.Operation) error {
+	key := workspace + "/" + repository
+	for _, v := range operations {
+		f.operations[key] = append(f.operations[key], v.Message())
+		f.permissions[key] = api.ApplyOperation(f.permissions[key], v)
+	}
+	return nil
+}
+
+func (f *fakeBackend) ListDefaultReviewers(ctx context.Context, workspace, repository string) ([]api.Account, error) {
+	return f.reviewers[workspace+"/"+repository], nil
+}
+
+func (f *fakeBackend) AddDefaultReviewers(ctx context.Context, workspace, repository string, reviewers []string) ([]api.Account, error) {
+	if f.reviewerErr != nil {
+		return nil, f.reviewerErr
+	}
+	key := workspace + "/" + repository
+	f.addedReviewers[key] = append(f.addedReviewers[key], reviewers...)
+	return []api.Account{}, nil
+}
+
+func (f *fakeBackend) DeleteDefaultReviewers(ctx context.Context, workspace, repository string, reviewers []string) ([]api.Account, error) {
+	if f.reviewerErr != nil {
+		return nil, f.reviewerErr
+	}
+	key := workspace + "/" + repository
+	f.deletedReviewers[key] = append(f.deletedReviewers[key], reviewers...)
+	return []api.Account{}, nil
+}
+
+func (f *fakeBackend) ListBranchRestrictions(ctx context.Context, workspace, repository string) ([]api.BranchRestriction, error) {
+	return f.branchRestrictions[workspace+"/"+repository], nil
+}
+
+func (f *fakeBackend) UpdateBranchRestrictions(ctx context.Context, workspace, repository string, operations []api.BranchRestrictionOperation) error {
+	key := workspace + "/" + repository
+	for _, v := range operations {
+		f.branchOperations[key] = append(f.branchOperations[key], v.Message())
+	}
+	return nil
+}
+
+func (f *fakeBackend) GetBranchingModel(ctx context.Context, workspace, repository string) (api.BranchingModel, error) {
+	return f.branchingModels[workspace+"/"+repository], nil
+}
+
+func (f *fakeBackend) UpdateBranchingModel(ctx context.Context, workspace, repository string, changes []api.SettingChange) error {
+	key := workspace + "/" + repository
+	for _, v := range changes {
+		f.settingChanges[key] = append(f.settingChanges[key], v.Message())
+	}
+	return nil
+}
+
+func (f *fakeBackend) GetRepositorySettings(ctx context.Context, workspace, repository string) (api.RepositorySettings, error) {
+	return f.repositorySettings[workspace+"/"+repository], nil
+}
+
+func (f *fakeBackend) UpdateRepositorySettings(ctx context.Context, workspace, repository string, changes []api.SettingChange) error {
+	key := workspace + "/" + repository
+	for _, v := range changes {
+		f.settingChanges[key] = append(f.settingChanges[key], v.Message())
+	}
+	return nil
+}
+
+func (f *fakeBackend) ListWebhooks(ctx context.Context, workspace, repository string) ([]api.Webhook, error) {
+	return f.webhooks[workspace+"/"+repository], nil
+}
+
+func (f *fakeBackend) UpdateWebhooks(ctx context.Context, workspace, repository string, operations []api.WebhookOperation) error {
+	key := workspace + "/" + repository
+	for _, v := range operations {
+		f.webhookOperations[key] = append(f.webhookOperations[key], v.Message())
+	}
+	return nil
+}
+
+// resetCommands restores flags and contexts of the command and its subcommands,
+// since commands are shared between tests and cobra keeps the context of the previous execution.
+func resetCommands(cmd *cobra.Command) {
+	cmd.SetContext(nil)
+	for _, flags := range []*pflag.FlagSet{cmd.Flags(), cmd.PersistentFlags()} {
+		flags.VisitAll(func(f *pflag.Flag) {
+			if v, ok := f.Value.(pflag.SliceValue); ok {
+				v.Replace(nil)
+			} else {
+				f.Value.Set(f.DefValue)
+			}
+			f.Changed = false
+		})
+	}
+	for _, v := range cmd.Commands() {
+		resetCommands(v)
+	}
+}
+
+// executeCommand runs the command with args against the backend, isolated from the user's config.
+func executeCommand(t *testing.T, backend api.Backend, args ...string) error {
+	t.Helper()
+	return executeCommandWithConfig(t, backend, "", args...)
+}
+
+// executeCommandWithConfig runs the command like executeCommand with config.toml of the content.
+func executeCommandWithConfig(t *testing.T, backend api.Backend, config string, args ...string) error {
+	t.Helper()
+	return executeCommandWithOptions(t, config, args, WithBackend(backend))
+}
+
+// executeCommandWithOptions runs the command with config.toml of the content and options of Execute.
+func executeCommandWithOptions(t *testing.T, config string, args []string, opts ...Option) error {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	t.Cleanup(viper.Reset)
+
+	if config != "" {
+		dir := filepath.Join(configHome, "bbdan")
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "config.toml"), []byte(config), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resetCommands(rootCmd)
+	rootCmd.SetArgs(args)
+	return Execute(opts...)
+}
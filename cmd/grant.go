@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/ikorihn/bbdan/api"
+	"github.com/spf13/cobra"
+)
+
+// grantCmd represents the grant command
+var grantCmd = &cobra.Command{
+	Use:   "grant workspace repository",
+	Short: "Grant permission to users or groups without prompts",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		workspace := args[0]
+		repository := args[1]
+
+		principals := getPrincipals(cmd)
+		if len(principals) == 0 {
+			return fmt.Errorf("--user or --group is required")
+		}
+		p, _ := cmd.Flags().GetString("permission")
+		permission, err := api.ParsePermissionType(p)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Grant %s permission of %s/%s\n", permission, workspace, repository)
+
+		ba, err := newBackend()
+		if err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+		permissions, err := ba.ListPermission(ctx, workspace, repository)
+		if err != nil {
+			fmt.Printf("%v", err)
+			return err
+		}
+
+		operations, err := grantOperations(permissions, api.Filter{}, principals, permission)
+		if err != nil {
+			return err
+		}
+
+		for _, v := range operations {
+			fmt.Println(v.Message())
+		}
+		err = ba.UpdatePermissions(ctx, workspace, repository, operations)
+		if err != nil {
+			printUpdateError[api.Operation](err)
+			return err
+		}
+
+		showPermissions(ctx, ba, workspace, repository)
+
+		return nil
+	},
+}
+
+func init() {
+	permissionCmd.AddCommand(grantCmd)
+	addPrincipalFlags(grantCmd)
+	grantCmd.Flags().StringP("permission", "p", "", "Permission to grant: read|write|admin")
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/ikorihn/bbdan/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGrantCmd(t *testing.T) {
+	fb := newFakeBackend()
+	fb.permissions["myworkspace/myrepository"] = []api.Permission{
+		{ObjectId: "developer", ObjectName: "developer", ObjectType: api.ObjectTypeGroup, PermissionType: api.PermissionTypeWrite},
+		{ObjectId: "{1111}", ObjectName: "john-doe", ObjectType: api.ObjectTypeUser, PermissionType: api.PermissionTypeRead},
+	}
+
+	err := executeCommand(t, fb, "permission", "grant", "myworkspace", "myrepository",
+		"--user", "john-doe", "--user", "{2222}", "--group", "developer", "--permission", "write")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"myworkspace/myrepository": {
+			"Update: user john-doe READ => WRITE",
+			"Add: user {2222} (WRITE)",
+		},
+	}, fb.operations)
+}
+
+func TestGrantCmd_invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{
+			name:    "no principal",
+			args:    []string{"--permission", "read"},
+			wantErr: "--user or --group is required",
+		},
+		{
+			name:    "invalid permission",
+			args:    []string{"--user", "{2222}", "--permission", "owner"},
+			wantErr: `invalid permission "owner": must be one of read, write, admin`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fb := newFakeBackend()
+			args := append([]string{"permission", "grant", "myworkspace", "myrepository"}, tt.args...)
+			err := executeCommand(t, fb, args...)
+			assert.EqualError(t, err, tt.wantErr)
+			assert.Empty(t, fb.operations)
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/ikorihn/bbdan/api"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// lintCmd represents the lint command
+var lintCmd = &cobra.Command{
+	Use:   "lint workspace repository...",
+	Short: "Check permissions of repositories against rules",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		workspace := args[0]
+		repositories := args[1:]
+
+		rulesFile, _ := cmd.Flags().GetString("rules")
+		rules, err := loadLintRules(rulesFile)
+		if err != nil {
+			return err
+		}
+
+		fix, _ := cmd.Flags().GetBool("fix")
+		batch, _ := cmd.Flags().GetBool("batch")
+
+		ba, err := newBackend()
+		if err != nil {
+			return err
+		}
+
+		ctx := cmd.Context()
+
+		remaining := 0
+		for _, repository := range repositories {
+			permissions, err := ba.ListPermission(ctx, workspace, repository)
+			if err != nil {
+				return err
+			}
+
+			violations := api.Lint(permissions, rules)
+
+			fmt.Printf("==== %s/%s ====\n", workspace, repository)
+			if len(violations) == 0 {
+				fmt.Println("OK")
+				continue
+			}
+			for _, v := range violations {
+				fmt.Printf("[%s] %s\n", v.Rule, v.Message)
+			}
+
+			if fix {
+				violations, err = fixViolations(cmd, ba, workspace, repository, rules, violations, batch)
+				if err != nil {
+					return err
+				}
+			}
+			remaining += len(violations)
+		}
+
+		if remaining > 0 {
+			return fmt.Errorf("%d violations found", remaining)
+		}
+		return nil
+	},
+}
+
+// fixViolations applies chosen operations to fix violations, and returns violations left after them.
+// Violations without fix and fixes not chosen remain.
+func fixViolations(cmd *cobra.Command, ba api.Backend, workspace, repository string, rules []api.LintRule, violations []api.Violation, batch bool) ([]api.Violation, error) {
+	ctx := cmd.Context()
+
+	operations := api.FixOperations(violations)
+	if len(operations) == 0 {
+		return violations, nil
+	}
+	operations, err := selectOperations(operations, batch)
+	if err != nil {
+		return nil, err
+	}
+	if len(operations) == 0 {
+		return violations, nil
+	}
+
+	err = ba.UpdatePermissions(ctx, workspace, repository, operations)
+	if err != nil {
+		printUpdateError[api.Operation](err)
+		return nil, err
+	}
+
+	// lint again, since a fix may not resolve all violations of the rule
+	permissions, err := ba.ListPermission(ctx, workspace, repository)
+	if err != nil {
+		return nil, err
+	}
+	return api.Lint(permissions, rules), nil
+}
+
+// loadLintRules reads rules from the file, or from `lint.rules` of config.toml if the file is empty.
+func loadLintRules(file string) ([]api.LintRule, error) {
+	var rules []api.LintRule
+	if file == "" {
+		if err := viper.UnmarshalKey("lint.rules", &rules); err != nil {
+			return nil, err
+		}
+	} else {
+		v := viper.New()
+		v.SetConfigFile(file)
+		if err := v.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("failed to read rules: %w", err)
+		}
+		if err := v.UnmarshalKey("rules", &rules); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("no lint rules configured")
+	}
+	for _, r := range rules {
+		if err := r.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return rules, nil
+}
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+	lintCmd.Flags().StringP("rules", "f", "", "Rules file. Defaults to [lint] section of config.toml")
+	lintCmd.Flags().Bool("fix", false, "Choose and apply operations to comply with rules")
+	lintCmd.Flags().BoolP("batch", "b", false, "With --fix, apply all operations without asking")
+}
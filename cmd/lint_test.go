@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/ikorihn/bbdan/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLintCmd_fix(t *testing.T) {
+	config := `
+[[lint.rules]]
+kind = "max-permission"
+object_type = "user"
+pattern = "*"
+permission = "write"
+
+[[lint.rules]]
+kind = "max-count"
+permission = "write"
+max = 1
+`
+	fb := newFakeBackend()
+	fb.permissions["myworkspace/myrepository"] = []api.Permission{
+		{ObjectId: "developer", ObjectName: "developer", ObjectType: api.ObjectTypeGroup, PermissionType: api.PermissionTypeWrite},
+		{ObjectId: "{1111}", ObjectName: "john-doe", ObjectType: api.ObjectTypeUser, PermissionType: api.PermissionTypeAdmin},
+	}
+
+	err := executeCommandWithConfig(t, fb, config, "lint", "myworkspace", "myrepository")
+	assert.EqualError(t, err, "2 violations found")
+	assert.Empty(t, fb.operations)
+
+	// max-count has no fix, so it remains after fixing max-permission
+	err = executeCommandWithConfig(t, fb, config, "lint", "--fix", "-b", "myworkspace", "myrepository")
+	assert.EqualError(t, err, "1 violations found")
+	assert.Equal(t, map[string][]string{
+		"myworkspace/myrepository": {"Update: user john-doe ADMIN => WRITE"},
+	}, fb.operations)
+}
@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"net/http"
 
 	"github.com/ikorihn/bbdan/api"
 	"github.com/spf13/cobra"
@@ -18,10 +17,22 @@ var listCmd = &cobra.Command{
 		repository := args[1]
 		fmt.Printf("List permissions for %s/%s\n", workspace, repository)
 
-		hc := http.DefaultClient
+		filter, err := getFilter(cmd)
+		if err != nil {
+			return err
+		}
 
-		ba := api.NewBitbucketApi(hc, username, password)
-		showPermissions(ba, workspace, repository)
+		ba, err := newBackend()
+		if err != nil {
+			return err
+		}
+		permissions, err := ba.ListPermission(cmd.Context(), workspace, repository)
+		if err != nil {
+			fmt.Printf("%v", err)
+			return err
+		}
+
+		printPermissions(api.FilterPermissions(permissions, filter))
 
 		return nil
 	},
@@ -29,4 +40,5 @@ var listCmd = &cobra.Command{
 
 func init() {
 	permissionCmd.AddCommand(listCmd)
+	addFilterFlags(listCmd)
 }
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+// newConfiguredHTTPClient creates the HTTP client from network settings of the current profile:
+// proxy, ca_bundle, insecure_skip_verify, connect_timeout, read_timeout and max_idle_conns.
+// The proxy defaults to HTTPS_PROXY and related environment variables.
+func newConfiguredHTTPClient() (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if v := configString("proxy"); v != "" {
+		proxy, err := url.Parse(v)
+		if err != nil || proxy.Host == "" {
+			return nil, fmt.Errorf("invalid proxy %q: must be a URL like http://proxy.example.com:8080", v)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if file := configString("ca_bundle"); file != "" {
+		pool, err := loadCABundle(file)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = pool
+	}
+	insecure, err := configBool("insecure_skip_verify")
+	if err != nil {
+		return nil, err
+	}
+	if insecure {
+		fmt.Fprintln(os.Stderr, "WARNING: insecure_skip_verify is enabled. TLS certificates are NOT verified and credentials can be intercepted. Use ca_bundle instead.")
+		tlsConfig.InsecureSkipVerify = true
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	connectTimeout, err := configDuration("connect_timeout")
+	if err != nil {
+		return nil, err
+	}
+	if connectTimeout > 0 {
+		dialer := &net.Dialer{
+			Timeout:   connectTimeout,
+			KeepAlive: 30 * time.Second,
+		}
+		transport.DialContext = dialer.DialContext
+		transport.TLSHandshakeTimeout = connectTimeout
+	}
+
+	readTimeout, err := configDuration("read_timeout")
+	if err != nil {
+		return nil, err
+	}
+	if readTimeout > 0 {
+		transport.ResponseHeaderTimeout = readTimeout
+	}
+
+	if v := configString("max_idle_conns"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid max_idle_conns %q: must be a number", v)
+		}
+		transport.MaxIdleConns = n
+		transport.MaxIdleConnsPerHost = n
+	}
+
+	if readTimeout > 0 {
+		return &http.Client{Transport: &readTimeoutTransport{next: transport, timeout: readTimeout}}, nil
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+// readTimeoutTransport cancels the request when reading the response body makes no progress for the timeout.
+// The timeout until the response header is ResponseHeaderTimeout of the transport.
+type readTimeoutTransport struct {
+	next    http.RoundTripper
+	timeout time.Duration
+}
+
+func (t *readTimeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	res, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	res.Body = &timeoutBody{
+		ReadCloser: res.Body,
+		timeout:    t.timeout,
+		timer:      time.AfterFunc(t.timeout, cancel),
+		cancel:     cancel,
+	}
+	return res, nil
+}
+
+// timeoutBody is a response body whose request is canceled by timer unless read again within timeout.
+type timeoutBody struct {
+	io.ReadCloser
+	timeout time.Duration
+	timer   *time.Timer
+	cancel  context.CancelFunc
+}
+
+func (b *timeoutBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil {
+		// the timer has already fired if it can't be stopped
+		if !b.timer.Stop() {
+			return n, fmt.Errorf("no response for read_timeout %v: %w", b.timeout, err)
+		}
+		return n, err
+	}
+	b.timer.Reset(b.timeout)
+	return n, nil
+}
+
+func (b *timeoutBody) Close() error {
+	b.timer.Stop()
+	b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// loadCABundle returns the system certificates with ones in the PEM file added.
+func loadCABundle(file string) (*x509.CertPool, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ca_bundle: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("no certificates in ca_bundle %s", file)
+	}
+	return pool, nil
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewConfiguredHTTPClient(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+	t.Cleanup(viper.Reset)
+
+	// the certificate of the server is unknown by default
+	hc, err := newConfiguredHTTPClient()
+	assert.NoError(t, err)
+	_, err = hc.Get(ts.URL)
+	assert.Error(t, err)
+
+	caBundle := filepath.Join(t.TempDir(), "ca.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	assert.NoError(t, os.WriteFile(caBundle, cert, 0o600))
+	viper.Set("ca_bundle", caBundle)
+	viper.Set("connect_timeout", "5s")
+	viper.Set("read_timeout", "10s")
+	viper.Set("max_idle_conns", 4)
+	viper.Set("proxy", "http://proxy.example.com:8080")
+
+	hc, err = newConfiguredHTTPClient()
+	assert.NoError(t, err)
+	transport := hc.Transport.(*readTimeoutTransport).next.(*http.Transport)
+	assert.Equal(t, 5*time.Second, transport.TLSHandshakeTimeout)
+	assert.Equal(t, 10*time.Second, transport.ResponseHeaderTimeout)
+	assert.Equal(t, 4, transport.MaxIdleConnsPerHost)
+	proxy, err := transport.Proxy(httptest.NewRequest("GET", "https://api.bitbucket.org/2.0/user", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, "proxy.example.com:8080", proxy.Host)
+
+	// the test server is requested directly
+	transport.Proxy = nil
+	res, err := hc.Get(ts.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+
+	viper.Set("read_timeout", "soon")
+	_, err = newConfiguredHTTPClient()
+	assert.Error(t, err)
+}
+
+func TestNewConfiguredHTTPClient_readTimeout(t *testing.T) {
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"values":[`)
+		w.(http.Flusher).Flush()
+		if r.URL.Path == "/slow" {
+			select {
+			case <-done:
+			case <-r.Context().Done():
+			}
+		}
+		fmt.Fprint(w, `]}`)
+	}))
+	defer ts.Close()
+	defer close(done)
+	t.Cleanup(viper.Reset)
+
+	viper.Set("read_timeout", "50ms")
+	hc, err := newConfiguredHTTPClient()
+	assert.NoError(t, err)
+
+	res, err := hc.Get(ts.URL + "/fast")
+	assert.NoError(t, err)
+	b, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"values":[]}`, string(b))
+
+	// the header is received in time, but the body stalls
+	res, err = hc.Get(ts.URL + "/slow")
+	assert.NoError(t, err)
+	_, err = io.ReadAll(res.Body)
+	res.Body.Close()
+	assert.ErrorContains(t, err, "no response for read_timeout 50ms")
+}
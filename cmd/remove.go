@@ -1,9 +1,7 @@
 package cmd
 
 import (
-	"context"
 	"fmt"
-	"net/http"
 
 	"github.com/ikorihn/bbdan/api"
 	"github.com/spf13/cobra"
@@ -19,33 +17,68 @@ var removeCmd = &cobra.Command{
 		repository := args[1]
 		fmt.Printf("Remove selected permissions from %s/%s\n", workspace, repository)
 
-		hc := http.DefaultClient
+		filter, err := getFilter(cmd)
+		if err != nil {
+			return err
+		}
 
-		ba := api.NewBitbucketApi(hc, username, password)
-		ctx := context.Background()
+		ba, err := newBackend()
+		if err != nil {
+			return err
+		}
+		ctx := cmd.Context()
 		permissions, err := ba.ListPermission(ctx, workspace, repository)
 		if err != nil {
 			fmt.Printf("%v", err)
 			return err
 		}
 
-		operations := []api.Operation{}
-		for _, v := range permissions {
-			o := api.NewRemoveOperation(v)
-			operations = append(operations, o)
-		}
+		var selectedOperations []api.Operation
+		if principals := getPrincipals(cmd); len(principals) > 0 {
+			for _, v := range principals {
+				current, ok, err := resolvePrincipal(permissions, filter, v)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					return fmt.Errorf("%s %s has no permission", v.ObjectType, v.ObjectId)
+				}
+				selectedOperations = append(selectedOperations, api.NewRemoveOperation(current))
+			}
+			for _, v := range selectedOperations {
+				fmt.Println(v.Message())
+			}
 
-		selectedOperations, err := askOperation(operations)
-		if err != nil {
-			return err
+			yes, _ := cmd.Flags().GetBool("yes")
+			if !yes {
+				confirmed, err := askConfirm("Remove these permissions?")
+				if err != nil {
+					return err
+				}
+				if !confirmed {
+					return nil
+				}
+			}
+		} else {
+			operations := []api.Operation{}
+			for _, v := range api.FilterPermissions(permissions, filter) {
+				o := api.NewRemoveOperation(v)
+				operations = append(operations, o)
+			}
+
+			selectedOperations, err = askOperation(operations)
+			if err != nil {
+				return err
+			}
 		}
+
 		err = ba.UpdatePermissions(ctx, workspace, repository, selectedOperations)
 		if err != nil {
-			fmt.Printf("Failed to update: %v\n", err)
+			printUpdateError[api.Operation](err)
 			return err
 		}
 
-		showPermissions(ba, workspace, repository)
+		showPermissions(ctx, ba, workspace, repository)
 
 		return nil
 	},
@@ -53,4 +86,7 @@ var removeCmd = &cobra.Command{
 
 func init() {
 	permissionCmd.AddCommand(removeCmd)
+	addFilterFlags(removeCmd)
+	addPrincipalFlags(removeCmd)
+	removeCmd.Flags().BoolP("yes", "y", false, "With --user or --group, remove without confirmation")
 }
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/ikorihn/bbdan/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemoveCmd(t *testing.T) {
+	fb := newFakeBackend()
+	fb.permissions["myworkspace/myrepository"] = []api.Permission{
+		{ObjectId: "developer", ObjectName: "developer", ObjectType: api.ObjectTypeGroup, PermissionType: api.PermissionTypeWrite},
+		{ObjectId: "{1111}", ObjectName: "john-doe", ObjectType: api.ObjectTypeUser, PermissionType: api.PermissionTypeAdmin},
+	}
+
+	err := executeCommand(t, fb, "permission", "remove", "myworkspace", "myrepository", "--user", "john-doe", "--group", "developer", "--yes")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"myworkspace/myrepository": {
+			"Remove: user john-doe (ADMIN)",
+			"Remove: group developer (WRITE)",
+		},
+	}, fb.operations)
+
+	err = executeCommand(t, fb, "permission", "remove", "myworkspace", "myrepository", "--user", "nobody", "--yes")
+	assert.Error(t, err)
+}
+
+func TestRemoveCmd_principalsWithFilter(t *testing.T) {
+	fb := newFakeBackend()
+	fb.permissions["myworkspace/myrepository"] = []api.Permission{
+		{ObjectId: "developer", ObjectName: "developer", ObjectType: api.ObjectTypeGroup, PermissionType: api.PermissionTypeWrite},
+		{ObjectId: "{1111}", ObjectName: "john-doe", ObjectType: api.ObjectTypeUser, PermissionType: api.PermissionTypeAdmin},
+	}
+
+	err := executeCommand(t, fb, "permission", "remove", "myworkspace", "myrepository", "--include", "dev*", "--user", "john-doe", "--yes")
+	assert.EqualError(t, err, "user john-doe is excluded by filter")
+	assert.Empty(t, fb.operations)
+}
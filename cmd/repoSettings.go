@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ikorihn/bbdan/api"
+	"github.com/spf13/cobra"
+)
+
+// repoCmd represents the repo command
+var repoCmd = &cobra.Command{
+	Use:   "repo",
+	Short: "Manage repository",
+}
+
+// repoSettingsCmd represents the repo settings command
+var repoSettingsCmd = &cobra.Command{
+	Use:   "settings",
+	Short: "Show, copy, apply properties of repository",
+	Long: `Show, copy, apply properties of repository: is_private, fork_policy, mainbranch, language, description, has_issues and has_wiki.
+
+Default merge strategies are not covered, since Bitbucket Cloud only reports them on each branch and has no API to change them.`,
+}
+
+// showRepoSettingsCmd represents the repo settings show command
+var showRepoSettingsCmd = &cobra.Command{
+	Use:   "show workspace repository",
+	Short: "Show properties of a repository",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		workspace := args[0]
+		repository := args[1]
+		output, _ := cmd.Flags().GetString("output")
+
+		rs, err := newRepositorySettingsBackend()
+		if err != nil {
+			return err
+		}
+
+		settings, err := rs.GetRepositorySettings(cmd.Context(), workspace, repository)
+		if err != nil {
+			return err
+		}
+
+		switch output {
+		case "json":
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(settings)
+		case "", "text":
+			fmt.Printf("Show repository settings for %s/%s\n", workspace, repository)
+			printSettings(settings.Settings())
+			return nil
+		default:
+			return fmt.Errorf("invalid output %q: must be text or json", output)
+		}
+	},
+}
+
+// copyRepoSettingsCmd represents the repo settings copy command
+var copyRepoSettingsCmd = &cobra.Command{
+	Use:   "copy workspace source-repository target-repository",
+	Short: "Copy properties between repositories",
+	Long:  "Make properties of the target repository the same as the source, showing the difference of each field before applying",
+	Args:  cobra.ExactArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		workspace := args[0]
+		srcRepository := args[1]
+		targetRepository := args[2]
+		batch, _ := cmd.Flags().GetBool("batch")
+		fields, _ := cmd.Flags().GetStringSlice("field")
+
+		fmt.Printf("Copy repository settings from %s/%s to %s/%s\n", workspace, srcRepository, workspace, targetRepository)
+
+		rs, err := newRepositorySettingsBackend()
+		if err != nil {
+			return err
+		}
+
+		settings, err := rs.GetRepositorySettings(cmd.Context(), workspace, srcRepository)
+		if err != nil {
+			return err
+		}
+		return applyRepositorySettings(cmd, rs, workspace, targetRepository, settings, fields, batch)
+	},
+}
+
+// applyRepoSettingsCmd represents the repo settings apply command
+var applyRepoSettingsCmd = &cobra.Command{
+	Use:   "apply workspace repository...",
+	Short: "Apply properties of a JSON file to repositories",
+	Long: `Make properties of repositories the same as a JSON file, which is written by show --output json.
+
+{
+  "is_private": true,
+  "fork_policy": "no_public_forks",
+  "mainbranch": "main",
+  "language": "go",
+  "description": "",
+  "has_issues": false,
+  "has_wiki": false
+}`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		workspace := args[0]
+		repositories := args[1:]
+		file, _ := cmd.Flags().GetString("file")
+		batch, _ := cmd.Flags().GetBool("batch")
+		fields, _ := cmd.Flags().GetStringSlice("field")
+
+		settings, err := readRepositorySettings(file)
+		if err != nil {
+			return err
+		}
+
+		rs, err := newRepositorySettingsBackend()
+		if err != nil {
+			return err
+		}
+
+		for _, repository := range repositories {
+			fmt.Printf("Apply repository settings of %s to %s/%s\n", file, workspace, repository)
+			if err := applyRepositorySettings(cmd, rs, workspace, repository, settings, fields, batch); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+// newRepositorySettingsBackend returns the backend if it supports repository settings.
+func newRepositorySettingsBackend() (api.RepositorySettingsBackend, error) {
+	ba, err := newBackend()
+	if err != nil {
+		return nil, err
+	}
+	rs, ok := ba.(api.RepositorySettingsBackend)
+	if !ok {
+		return nil, errors.New("repository settings are supported only by Bitbucket Cloud")
+	}
+	return rs, nil
+}
+
+// applyRepositorySettings changes the selected fields of properties of the repository to settings.
+// If fields is not empty, only the fields are compared.
+func applyRepositorySettings(cmd *cobra.Command, rs api.RepositorySettingsBackend, workspace, repository string, settings api.RepositorySettings, fields []string, batch bool) error {
+	ctx := cmd.Context()
+
+	current, err := rs.GetRepositorySettings(ctx, workspace, repository)
+	if err != nil {
+		return err
+	}
+
+	changes, err := filterSettingChanges(api.MakeSettingChanges(settings.Settings(), current.Settings()), fields)
+	if err != nil {
+		return err
+	}
+	printSettingChanges(changes)
+	selectedChanges, err := selectOperations(changes, batch)
+	if err != nil {
+		return err
+	}
+	if len(selectedChanges) > 0 {
+		if err := rs.UpdateRepositorySettings(ctx, workspace, repository, selectedChanges); err != nil {
+			fmt.Printf("Failed to update: %v\n", err)
+			return err
+		}
+	}
+
+	result, err := rs.GetRepositorySettings(ctx, workspace, repository)
+	if err != nil {
+		return err
+	}
+	printSettings(result.Settings())
+	return nil
+}
+
+// filterSettingChanges returns changes of the fields. All changes are returned if fields is empty.
+func filterSettingChanges(changes []api.SettingChange, fields []string) ([]api.SettingChange, error) {
+	if len(fields) == 0 {
+		return changes, nil
+	}
+
+	filtered := make([]api.SettingChange, 0, len(fields))
+	for _, f := range fields {
+		found := false
+		for _, v := range changes {
+			if v.Field == f {
+				filtered = append(filtered, v)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown field %q", f)
+		}
+	}
+	return filtered, nil
+}
+
+func readRepositorySettings(file string) (api.RepositorySettings, error) {
+	if file == "" {
+		return api.RepositorySettings{}, errors.New("--file is required")
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return api.RepositorySettings{}, err
+	}
+
+	var settings api.RepositorySettings
+	if err := json.Unmarshal(b, &settings); err != nil {
+		return api.RepositorySettings{}, fmt.Errorf("invalid repository settings %s: %w", file, err)
+	}
+	if _, err := api.ParseForkPolicy(string(settings.ForkPolicy)); err != nil {
+		return api.RepositorySettings{}, fmt.Errorf("invalid repository settings %s: %w", file, err)
+	}
+	return settings, nil
+}
+
+func init() {
+	rootCmd.AddCommand(repoCmd)
+	repoCmd.AddCommand(repoSettingsCmd)
+	repoSettingsCmd.AddCommand(showRepoSettingsCmd)
+	repoSettingsCmd.AddCommand(copyRepoSettingsCmd)
+	repoSettingsCmd.AddCommand(applyRepoSettingsCmd)
+
+	showRepoSettingsCmd.Flags().StringP("output", "o", "text", "Output format: text|json. json can be used by apply")
+	copyRepoSettingsCmd.Flags().BoolP("batch", "b", false, "Execute in batch mode. Copy all without asking")
+	copyRepoSettingsCmd.Flags().StringSlice("field", nil, "Copy only the fields, e.g. fork_policy,is_private")
+	applyRepoSettingsCmd.Flags().StringP("file", "f", "", "JSON file of repository settings")
+	applyRepoSettingsCmd.Flags().BoolP("batch", "b", false, "Execute in batch mode. Apply all without asking")
+	applyRepoSettingsCmd.Flags().StringSlice("field", nil, "Apply only the fields, e.g. fork_policy,is_private")
+}
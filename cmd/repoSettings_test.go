@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ikorihn/bbdan/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRepositorySettingsFakeBackend() *fakeBackend {
+	fb := newFakeBackend()
+	fb.repositorySettings["myworkspace/src"] = api.RepositorySettings{
+		Private:    true,
+		ForkPolicy: api.ForkPolicyNoPublicForks,
+		MainBranch: "main",
+		Language:   "go",
+	}
+	fb.repositorySettings["myworkspace/target"] = api.RepositorySettings{
+		Private:     true,
+		ForkPolicy:  api.ForkPolicyAllowForks,
+		MainBranch:  "master",
+		Description: "target",
+		HasWiki:     true,
+	}
+	return fb
+}
+
+func TestRepoSettingsCopyCmd(t *testing.T) {
+	fb := newRepositorySettingsFakeBackend()
+	err := executeCommand(t, fb, "repo", "settings", "copy", "-b", "myworkspace", "src", "target")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"myworkspace/target": {
+			"Update: fork_policy: allow_forks => no_public_forks",
+			"Update: mainbranch: master => main",
+			`Update: language: "" => go`,
+			`Update: description: target => ""`,
+			"Update: has_wiki: true => false",
+		},
+	}, fb.settingChanges)
+}
+
+func TestRepoSettingsCopyCmd_field(t *testing.T) {
+	fb := newRepositorySettingsFakeBackend()
+	err := executeCommand(t, fb, "repo", "settings", "copy", "-b", "--field", "is_private,fork_policy", "myworkspace", "src", "target")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"myworkspace/target": {
+			"Update: fork_policy: allow_forks => no_public_forks",
+		},
+	}, fb.settingChanges)
+
+	err = executeCommand(t, fb, "repo", "settings", "copy", "-b", "--field", "name", "myworkspace", "src", "target")
+	assert.Error(t, err)
+}
+
+func TestRepoSettingsApplyCmd(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "settings.json")
+	err := os.WriteFile(file, []byte(`{"is_private": true, "fork_policy": "allow_forks", "mainbranch": "master", "description": "target", "has_wiki": true}`), 0o600)
+	assert.NoError(t, err)
+
+	fb := newRepositorySettingsFakeBackend()
+	err = executeCommand(t, fb, "repo", "settings", "apply", "-b", "-f", file, "myworkspace", "target")
+	assert.NoError(t, err)
+	assert.Empty(t, fb.settingChanges)
+
+	err = os.WriteFile(file, []byte(`{"fork_policy": "anyone"}`), 0o600)
+	assert.NoError(t, err)
+	err = executeCommand(t, fb, "repo", "settings", "apply", "-b", "-f", file, "myworkspace", "target")
+	assert.Error(t, err)
+}
@@ -1,8 +1,19 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
 
+	"github.com/ikorihn/bbdan/api"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -16,24 +27,472 @@ var rootCmd = &cobra.Command{
 var (
 	username string
 	password string
+	profile  string
+
+	recordDir string
+	replayDir string
+
+	parallelPages int
+
+	verbose   bool
+	debug     bool
+	logFormat string
+
+	noCache bool
+	refresh bool
+
+	timeout time.Duration
+	// cancelTimeout releases the timer of --timeout when the command ends.
+	cancelTimeout context.CancelFunc = func() {}
+
+	trace bool
+	// tracer is set by newHTTPClient with --trace to print the summary when the command ends.
+	tracer *api.Tracer
+
+	// configErr is an error while loading config.
+	// It is reported when a command needs credentials so that commands like `version` work without config.
+	configErr error
+)
+
+var errNoCredentials = errors.New("no credentials: configure username and password in $XDG_CONFIG_HOME/bbdan/config.toml or set BBDAN_USERNAME and BBDAN_PASSWORD")
+
+const (
+	flavorCloud      = "cloud"
+	flavorDataCenter = "datacenter"
+)
+
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
 )
 
+const (
+	authTypeBasic  = "basic"
+	authTypeBearer = "bearer"
+	authTypeOAuth2 = "oauth2"
+)
+
+// Option configures how commands access Bitbucket.
+type Option func(*rootOptions)
+
+type rootOptions struct {
+	backend    api.Backend
+	httpClient *http.Client
+	baseUrl    string
+	logger     *slog.Logger
+}
+
+// rootOpts is set by Execute and used by newBackend.
+var rootOpts rootOptions
+
+// WithBackend makes commands use the backend instead of creating one from config.
+func WithBackend(backend api.Backend) Option {
+	return func(o *rootOptions) {
+		o.backend = backend
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to access Bitbucket instead of one created from network settings of config.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(o *rootOptions) {
+		o.httpClient = hc
+	}
+}
+
+// WithBaseUrl overrides base_url of config. Unlike base_url of config, it doesn't make flavor default to datacenter.
+func WithBaseUrl(baseUrl string) Option {
+	return func(o *rootOptions) {
+		o.baseUrl = baseUrl
+	}
+}
+
+// WithLogger sets the logger of requests instead of one configured by --verbose, --debug and --log-format.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *rootOptions) {
+		o.logger = logger
+	}
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
-func Execute() error {
-	cobra.OnInitialize(func() {
-		viper.SetConfigName("config")
-		viper.SetConfigType("toml")
-		viper.AddConfigPath("$XDG_CONFIG_HOME/bbdan")
-		viper.AddConfigPath("$HOME/.config/bbdan")
-		err := viper.ReadInConfig()
+func Execute(opts ...Option) error {
+	resetState()
+	rootOpts = rootOptions{}
+	for _, opt := range opts {
+		opt(&rootOpts)
+	}
+
+	// the first interrupt cancels the context, and the next one kills the process as usual
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	context.AfterFunc(ctx, stop)
+
+	err := rootCmd.ExecuteContext(ctx)
+	cancelTimeout()
+	if tracer != nil {
+		fmt.Fprintln(os.Stderr)
+		tracer.Summary(os.Stderr)
+	}
+	return err
+}
+
+// resetState clears state left by the previous Execute, so that it is not carried over to the next one.
+// profile is set again by the flag or initConfig.
+func resetState() {
+	username = ""
+	password = ""
+	profile = ""
+	configErr = nil
+	tracer = nil
+	cancelTimeout = func() {}
+}
+
+func init() {
+	cobra.OnInitialize(initConfig)
+
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "Profile in config.toml to use. Defaults to $BBDAN_PROFILE or default_profile")
+	rootCmd.PersistentFlags().StringVar(&recordDir, "record", "", "Record HTTP requests and responses to the directory with credentials redacted")
+	rootCmd.PersistentFlags().StringVar(&replayDir, "replay", "", "Replay HTTP responses recorded by --record from the directory instead of accessing Bitbucket")
+	rootCmd.MarkFlagsMutuallyExclusive("record", "replay")
+	rootCmd.PersistentPreRun = applyTimeout
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Log requests to stderr")
+	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "Log requests to stderr with headers, credentials redacted")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", logFormatText, "Format of logs: text|json")
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 0, "Cancel the command after the duration, e.g. 30s or 5m. No timeout if 0")
+	rootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "Do not use cached responses")
+	rootCmd.PersistentFlags().BoolVar(&refresh, "refresh", false, "Fetch responses again and update the cache")
+	rootCmd.MarkFlagsMutuallyExclusive("no-cache", "refresh")
+	rootCmd.PersistentFlags().BoolVar(&trace, "trace", false, "Print timing of each request to stderr and the summary when the command ends")
+	rootCmd.PersistentFlags().IntVar(&parallelPages, "parallel-pages", 0, "Fetch up to the number of pages of large listings concurrently")
+}
+
+// applyTimeout sets the deadline of --timeout to the context of the command.
+func applyTimeout(cmd *cobra.Command, args []string) {
+	if timeout <= 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+	cancelTimeout = cancel
+	cmd.SetContext(ctx)
+}
+
+func initConfig() {
+	viper.SetConfigName("config")
+	viper.SetConfigType("toml")
+	viper.AddConfigPath("$XDG_CONFIG_HOME/bbdan")
+	viper.AddConfigPath("$HOME/.config/bbdan")
+	err := viper.ReadInConfig()
+	if err != nil {
+		var notFound viper.ConfigFileNotFoundError
+		if !errors.As(err, &notFound) {
+			configErr = fmt.Errorf("failed to read config file: %w", err)
+			return
+		}
+	}
+
+	if profile == "" {
+		profile = os.Getenv("BBDAN_PROFILE")
+	}
+	if profile == "" {
+		profile = viper.GetString("default_profile")
+	}
+	if profile != "" && !viper.IsSet("profiles."+profile) {
+		configErr = fmt.Errorf("profile %s is not defined in config file", profile)
+		return
+	}
+
+	username = configString("username")
+	password = configString("password")
+
+	if v, ok := os.LookupEnv("BBDAN_USERNAME"); ok {
+		username = v
+	}
+	if v, ok := os.LookupEnv("BBDAN_PASSWORD"); ok {
+		password = v
+	}
+}
+
+// resolvePassword reads the password by password_command or credential_helper of the current profile
+// unless it is given directly. It is called only when credentials are needed, so that commands
+// without access to Bitbucket don't run them.
+func resolvePassword() error {
+	if password != "" {
+		return nil
+	}
+	if command := configString("password_command"); command != "" {
+		p, err := runPasswordCommand(command)
 		if err != nil {
-			panic(fmt.Errorf("fatal error config file: %w", err))
+			return err
+		}
+		password = p
+		return nil
+	}
+	if helper := configString("credential_helper"); helper != "" {
+		// base_url of Bitbucket Cloud is the API, not the host of git remotes
+		baseUrl := configString("base_url")
+		if configString("flavor") == flavorCloud {
+			baseUrl = ""
 		}
+		u, p, err := runCredentialHelper(helper, baseUrl, username)
+		if err != nil {
+			return err
+		}
+		username, password = u, p
+	}
+	return nil
+}
 
-		username = viper.GetString("username")
-		password = viper.GetString("password")
-	})
+// configString returns the value of the key in the current profile.
+// It falls back to the top level key if the profile does not have it.
+func configString(key string) string {
+	if profile != "" {
+		k := fmt.Sprintf("profiles.%s.%s", profile, key)
+		if viper.IsSet(k) {
+			return viper.GetString(k)
+		}
+	}
+	return viper.GetString(key)
+}
+
+// configBool returns the boolean value of the key in the current profile like configString.
+func configBool(key string) (bool, error) {
+	v := configString(key)
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s %q: must be true or false", key, v)
+	}
+	return b, nil
+}
+
+// configDuration returns the duration value of the key in the current profile like configString, e.g. "30s".
+func configDuration(key string) (time.Duration, error) {
+	v := configString(key)
+	if v == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %w", key, v, err)
+	}
+	return d, nil
+}
+
+// currentAuthType returns auth_type of the current profile. Defaults to basic.
+func currentAuthType() string {
+	if v := configString("auth_type"); v != "" {
+		return v
+	}
+	return authTypeBasic
+}
+
+// newAuthenticator creates the authenticator selected by auth_type of the current profile.
+// Secrets of bearer and oauth2 fall back to the password so that password_command and credential_helper work for them.
+func newAuthenticator(hc *http.Client) (api.Authenticator, error) {
+	authType := currentAuthType()
+	switch authType {
+	case authTypeBasic:
+		if err := resolvePassword(); err != nil {
+			return nil, err
+		}
+		if username == "" || password == "" {
+			return nil, errNoCredentials
+		}
+		return api.BasicAuth{Username: username, Password: password}, nil
+
+	case authTypeBearer:
+		token := configString("token")
+		if v, ok := os.LookupEnv("BBDAN_TOKEN"); ok {
+			token = v
+		}
+		if token == "" {
+			if err := resolvePassword(); err != nil {
+				return nil, err
+			}
+			token = password
+		}
+		if token == "" {
+			return nil, errors.New("no credentials: configure token or set BBDAN_TOKEN")
+		}
+		return api.BearerToken{Token: token}, nil
+
+	case authTypeOAuth2:
+		clientId := configString("client_id")
+		clientSecret := configString("client_secret")
+		if clientSecret == "" && clientId != "" {
+			if err := resolvePassword(); err != nil {
+				return nil, err
+			}
+			clientSecret = password
+		}
+		if clientId == "" || clientSecret == "" {
+			return nil, errors.New("no credentials: configure client_id and client_secret")
+		}
+		return api.NewOAuth2ClientCredentials(hc, clientId, clientSecret), nil
+
+	default:
+		return nil, fmt.Errorf("invalid auth_type %q: must be one of basic, bearer, oauth2", authType)
+	}
+}
+
+// newHTTPClient creates the HTTP client to access Bitbucket, recording or replaying if requested.
+// With --trace, timing is measured around recording and replaying.
+func newHTTPClient() (*http.Client, error) {
+	hc := rootOpts.httpClient
+	if hc == nil {
+		c, err := newConfiguredHTTPClient()
+		if err != nil {
+			return nil, err
+		}
+		hc = c
+	}
 
-	return rootCmd.Execute()
+	transport := hc.Transport
+	switch {
+	case recordDir != "":
+		rec, err := api.NewRecorder(recordDir, transport)
+		if err != nil {
+			return nil, err
+		}
+		transport = rec
+	case replayDir != "":
+		rep, err := api.NewReplayer(replayDir)
+		if err != nil {
+			return nil, err
+		}
+		transport = rep
+	}
+	if trace {
+		if tracer == nil {
+			tracer = api.NewTracer(transport, os.Stderr)
+		}
+		transport = tracer
+	}
+
+	if transport == hc.Transport {
+		return hc, nil
+	}
+	c := *hc
+	c.Transport = transport
+	return &c, nil
+}
+
+// newLogger creates the logger of requests writing to stderr.
+// Only warnings are logged unless --verbose or --debug.
+func newLogger() (*slog.Logger, error) {
+	if rootOpts.logger != nil {
+		return rootOpts.logger, nil
+	}
+
+	level := slog.LevelWarn
+	switch {
+	case debug:
+		level = slog.LevelDebug
+	case verbose:
+		level = slog.LevelInfo
+	}
+	handlerOpts := &slog.HandlerOptions{Level: level}
+
+	switch logFormat {
+	case "", logFormatText:
+		return slog.New(slog.NewTextHandler(os.Stderr, handlerOpts)), nil
+	case logFormatJSON:
+		return slog.New(slog.NewJSONHandler(os.Stderr, handlerOpts)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q: must be text or json", logFormat)
+	}
+}
+
+// newCache creates the cache of responses in the user cache directory with cache_ttl of the current profile.
+// It returns nil unless cache_ttl is configured, since commands changing repositories would compare
+// with cached state which may be changed by others. The cache is also disabled by --no-cache,
+// or recording and replaying that need actual requests.
+func newCache() (*api.Cache, error) {
+	if noCache || recordDir != "" || replayDir != "" {
+		return nil, nil
+	}
+
+	ttl, err := configDuration("cache_ttl")
+	if err != nil {
+		return nil, err
+	}
+	if ttl <= 0 {
+		return nil, nil
+	}
+
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return api.NewCache(filepath.Join(dir, "bbdan"), ttl, refresh), nil
+}
+
+// newBackend creates a client of the Bitbucket product selected by flavor and base_url of the current profile.
+// flavor defaults to datacenter if base_url is set in config, otherwise cloud.
+func newBackend() (api.Backend, error) {
+	if rootOpts.backend != nil {
+		return rootOpts.backend, nil
+	}
+	if configErr != nil && replayDir == "" {
+		return nil, configErr
+	}
+
+	hc, err := newHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	auth, err := newAuthenticator(hc)
+	if err != nil {
+		// credentials are not needed to replay
+		if replayDir == "" {
+			return nil, err
+		}
+		auth = api.BasicAuth{}
+	}
+
+	flavor := configString("flavor")
+	baseUrl := configString("base_url")
+	if flavor == "" && baseUrl != "" {
+		flavor = flavorDataCenter
+	}
+	if rootOpts.baseUrl != "" {
+		baseUrl = rootOpts.baseUrl
+	}
+
+	opts := make([]api.Option, 0)
+	if baseUrl != "" {
+		opts = append(opts, api.WithBaseUrl(baseUrl))
+	}
+	logger, err := newLogger()
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, api.WithLogger(logger))
+	cache, err := newCache()
+	if err != nil {
+		return nil, err
+	}
+	if cache != nil {
+		opts = append(opts, api.WithCache(cache))
+	}
+	if parallelPages > 1 {
+		opts = append(opts, api.WithParallelPages(parallelPages))
+	}
+
+	switch flavor {
+	case "", flavorCloud:
+		return api.NewBitbucketApiWithAuth(hc, auth, opts...), nil
+	case flavorDataCenter:
+		if baseUrl == "" {
+			return nil, errors.New("base_url is required for Bitbucket Data Center")
+		}
+		if parallelPages > 1 {
+			return nil, errors.New("--parallel-pages is not supported by Bitbucket Data Center, which does not report the total number of pages")
+		}
+		return api.NewDataCenterApi(hc, baseUrl, auth, opts...), nil
+	default:
+		return nil, fmt.Errorf("invalid flavor %q: must be cloud or datacenter", flavor)
+	}
 }
@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/ikorihn/bbdan/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInitConfig_profile(t *testing.T) {
+	profiles := `
+[profiles.work]
+username = "work"
+password = "work-pass"
+
+[profiles.personal]
+username = "personal"
+`
+	tests := []struct {
+		name         string
+		config       string
+		env          map[string]string
+		args         []string
+		wantProfile  string
+		wantUsername string
+		wantPassword string
+		wantErr      string
+	}{
+		{
+			name:         "top level without profile",
+			config:       `username = "top"` + "\n" + `password = "top-pass"` + "\n" + profiles,
+			wantUsername: "top",
+			wantPassword: "top-pass",
+		},
+		{
+			name:         "default_profile",
+			config:       `username = "top"` + "\n" + `default_profile = "work"` + "\n" + profiles,
+			wantProfile:  "work",
+			wantUsername: "work",
+			wantPassword: "work-pass",
+		},
+		{
+			name:         "BBDAN_PROFILE over default_profile",
+			config:       `password = "top-pass"` + "\n" + `default_profile = "work"` + "\n" + profiles,
+			env:          map[string]string{"BBDAN_PROFILE": "personal"},
+			wantProfile:  "personal",
+			wantUsername: "personal",
+			wantPassword: "top-pass",
+		},
+		{
+			name:         "flag over BBDAN_PROFILE",
+			config:       `default_profile = "personal"` + "\n" + profiles,
+			env:          map[string]string{"BBDAN_PROFILE": "personal"},
+			args:         []string{"--profile", "work"},
+			wantProfile:  "work",
+			wantUsername: "work",
+			wantPassword: "work-pass",
+		},
+		{
+			name:         "env credentials over profile",
+			config:       `default_profile = "work"` + "\n" + profiles,
+			env:          map[string]string{"BBDAN_USERNAME": "env", "BBDAN_PASSWORD": "env-pass"},
+			wantProfile:  "work",
+			wantUsername: "env",
+			wantPassword: "env-pass",
+		},
+		{
+			name:         "env credentials without config",
+			env:          map[string]string{"BBDAN_USERNAME": "env", "BBDAN_PASSWORD": "env-pass"},
+			wantUsername: "env",
+			wantPassword: "env-pass",
+		},
+		{
+			name:        "undefined profile",
+			config:      profiles,
+			env:         map[string]string{"BBDAN_PROFILE": "unknown"},
+			wantProfile: "unknown",
+			wantErr:     "profile unknown is not defined in config file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, k := range []string{"BBDAN_PROFILE", "BBDAN_USERNAME", "BBDAN_PASSWORD"} {
+				t.Setenv(k, "")
+				os.Unsetenv(k)
+			}
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			args := append(tt.args, "version")
+			err := executeCommandWithConfig(t, newFakeBackend(), tt.config, args...)
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.wantProfile, profile)
+			if tt.wantErr != "" {
+				assert.EqualError(t, configErr, tt.wantErr)
+				return
+			}
+			assert.NoError(t, configErr)
+			assert.Equal(t, tt.wantUsername, username)
+			assert.Equal(t, tt.wantPassword, password)
+		})
+	}
+}
+
+func TestExecute_resetState(t *testing.T) {
+	t.Setenv("BBDAN_PROFILE", "unknown")
+	err := executeCommandWithConfig(t, newFakeBackend(), `username = "top"`, "version")
+	assert.NoError(t, err)
+	assert.Error(t, configErr)
+
+	tracer = api.NewTracer(http.DefaultTransport, io.Discard)
+	t.Setenv("BBDAN_PROFILE", "")
+	err = executeCommandWithConfig(t, newFakeBackend(), `username = "top"`, "version")
+	assert.NoError(t, err)
+	assert.NoError(t, configErr)
+	assert.Equal(t, "", profile)
+	assert.Equal(t, "top", username)
+	assert.Nil(t, tracer)
+}
+
+func TestNewBackend_flavor(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		baseUrl string
+		want    any
+	}{
+		{
+			name: "cloud by default",
+			want: &api.BitbucketApi{},
+		},
+		{
+			name:   "datacenter by base_url of config",
+			config: `base_url = "https://bitbucket.example.com"`,
+			want:   &api.DataCenterApi{},
+		},
+		{
+			name:    "cloud with base url option",
+			baseUrl: "http://127.0.0.1:8080",
+			want:    &api.BitbucketApi{},
+		},
+		{
+			name:    "datacenter of config with base url option",
+			config:  `flavor = "datacenter"`,
+			baseUrl: "http://127.0.0.1:8080",
+			want:    &api.DataCenterApi{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("BBDAN_USERNAME", "user")
+			t.Setenv("BBDAN_PASSWORD", "pass")
+			t.Setenv("XDG_CACHE_HOME", t.TempDir())
+			err := executeCommandWithConfig(t, newFakeBackend(), tt.config, "version")
+			assert.NoError(t, err)
+
+			rootOpts = rootOptions{baseUrl: tt.baseUrl}
+			t.Cleanup(func() { rootOpts = rootOptions{} })
+			got, err := newBackend()
+			assert.NoError(t, err)
+			assert.IsType(t, tt.want, got)
+		})
+	}
+}
+
+func TestNewBackend_parallelPagesDataCenter(t *testing.T) {
+	t.Setenv("BBDAN_USERNAME", "user")
+	t.Setenv("BBDAN_PASSWORD", "pass")
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	err := executeCommandWithConfig(t, newFakeBackend(), `base_url = "https://bitbucket.example.com"`, "version")
+	assert.NoError(t, err)
+
+	rootOpts = rootOptions{}
+	parallelPages = 4
+	t.Cleanup(func() { parallelPages = 0 })
+	_, err = newBackend()
+	assert.ErrorContains(t, err, "--parallel-pages is not supported by Bitbucket Data Center")
+}
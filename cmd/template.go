@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/ikorihn/bbdan/api"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// templateCmd represents the template command
+var templateCmd = &cobra.Command{
+	Use:   "template",
+	Short: "Apply permission templates defined in config.toml",
+}
+
+// templateApplyCmd represents the template apply command
+var templateApplyCmd = &cobra.Command{
+	Use:   "apply template workspace repository...",
+	Short: "Apply permissions of a template to repositories",
+	Args:  cobra.MinimumNArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		workspace := args[1]
+		repositories := args[2:]
+
+		templatePermissions, err := loadTemplate(name)
+		if err != nil {
+			return err
+		}
+		s, _ := cmd.Flags().GetString("strategy")
+		strategy, err := api.ParseStrategy(s)
+		if err != nil {
+			return err
+		}
+
+		batch, _ := cmd.Flags().GetBool("batch")
+
+		ba, err := newBackend()
+		if err != nil {
+			return err
+		}
+
+		ctx := cmd.Context()
+
+		for _, repository := range repositories {
+			fmt.Printf("Apply template %s to %s/%s\n", name, workspace, repository)
+
+			targetPermissions, err := ba.ListPermission(ctx, workspace, repository)
+			if err != nil {
+				return err
+			}
+
+			operations := api.MakeOperationListWithStrategy(templatePermissions, targetPermissions, strategy)
+
+			selectedOperations, err := selectOperations(operations, batch)
+			if err != nil {
+				return err
+			}
+
+			err = ba.UpdatePermissions(ctx, workspace, repository, selectedOperations)
+			if err != nil {
+				printUpdateError[api.Operation](err)
+				return err
+			}
+
+			showPermissions(ctx, ba, workspace, repository)
+		}
+
+		return nil
+	},
+}
+
+// templatePermission is a permission of a template.
+type templatePermission struct {
+	ObjectType api.ObjectType `mapstructure:"object_type"`
+	ObjectId   string         `mapstructure:"object_id"`
+	Permission string         `mapstructure:"permission"`
+}
+
+// loadTemplate builds permissions from `[[templates.<name>.permissions]]` of config.toml.
+// Permissions are a list of tables rather than a table keyed by object id, since keys of tables are lowercased by viper.
+//
+//	[[templates.backend.permissions]]
+//	object_type = "group"
+//	object_id = "developer"
+//	permission = "write"
+func loadTemplate(name string) ([]api.Permission, error) {
+	key := "templates." + name
+	if !viper.IsSet(key) {
+		return nil, fmt.Errorf("template %s is not defined", name)
+	}
+	if viper.IsSet(key+".groups") || viper.IsSet(key+".users") {
+		return nil, fmt.Errorf("template %s: tables of groups and users are not supported. Use [[%s.permissions]] with object_type, object_id and permission", name, key)
+	}
+
+	var entries []templatePermission
+	if err := viper.UnmarshalKey(key+".permissions", &entries); err != nil {
+		return nil, fmt.Errorf("template %s: %w", name, err)
+	}
+
+	permissions := make([]api.Permission, 0, len(entries))
+	for _, v := range entries {
+		if v.ObjectType != api.ObjectTypeGroup && v.ObjectType != api.ObjectTypeUser {
+			return nil, fmt.Errorf("template %s: invalid object_type %q for %s: must be group or user", name, v.ObjectType, v.ObjectId)
+		}
+		if v.ObjectId == "" {
+			return nil, fmt.Errorf("template %s: object_id is required", name)
+		}
+		p := api.PermissionType(v.Permission)
+		if p.Level() == 0 {
+			return nil, fmt.Errorf("template %s: invalid permission %q for %s %s", name, v.Permission, v.ObjectType, v.ObjectId)
+		}
+		if _, ok := api.FindPermission(permissions, v.ObjectType, v.ObjectId); ok {
+			return nil, fmt.Errorf("template %s: %s %s is duplicated", name, v.ObjectType, v.ObjectId)
+		}
+		permissions = append(permissions, api.Permission{
+			ObjectId:       v.ObjectId,
+			ObjectName:     v.ObjectId,
+			ObjectType:     v.ObjectType,
+			PermissionType: p,
+		})
+	}
+
+	return permissions, nil
+}
+
+func init() {
+	permissionCmd.AddCommand(templateCmd)
+	templateCmd.AddCommand(templateApplyCmd)
+	templateApplyCmd.Flags().BoolP("batch", "b", false, "Execute in batch mode. Apply all without asking")
+	templateApplyCmd.Flags().StringP("strategy", "s", string(api.StrategyAdditive), "How to merge permissions of the template: mirror|additive|max|min")
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/ikorihn/bbdan/api"
+	"github.com/stretchr/testify/assert"
+)
+
+const templateConfig = `
+[[templates.backend.permissions]]
+object_type = "group"
+object_id = "developer"
+permission = "write"
+[[templates.backend.permissions]]
+object_type = "user"
+object_id = "{1111}"
+permission = "admin"
+
+[[templates.mixed-case.permissions]]
+object_type = "group"
+object_id = "DevOps"
+permission = "read"
+
+[[templates.broken.permissions]]
+object_type = "group"
+object_id = "developer"
+permission = "owner"
+
+[templates.legacy.groups]
+developer = "write"
+`
+
+func TestTemplateApplyCmd(t *testing.T) {
+	fb := newFakeBackend()
+	fb.permissions["myworkspace/target"] = []api.Permission{
+		{ObjectId: "developer", ObjectName: "developer", ObjectType: api.ObjectTypeGroup, PermissionType: api.PermissionTypeRead},
+		{ObjectId: "{2222}", ObjectName: "bot-ci", ObjectType: api.ObjectTypeUser, PermissionType: api.PermissionTypeWrite},
+	}
+
+	err := executeCommandWithConfig(t, fb, templateConfig, "permission", "template", "apply", "-b", "backend", "myworkspace", "target", "empty")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"myworkspace/target": {
+			"Update: group developer READ => WRITE",
+			"Add: user {1111} (ADMIN)",
+		},
+		"myworkspace/empty": {
+			"Add: group developer (WRITE)",
+			"Add: user {1111} (ADMIN)",
+		},
+	}, fb.operations)
+}
+
+func TestTemplateApplyCmd_strategy(t *testing.T) {
+	fb := newFakeBackend()
+	fb.permissions["myworkspace/target"] = []api.Permission{
+		{ObjectId: "developer", ObjectName: "developer", ObjectType: api.ObjectTypeGroup, PermissionType: api.PermissionTypeRead},
+		{ObjectId: "{2222}", ObjectName: "bot-ci", ObjectType: api.ObjectTypeUser, PermissionType: api.PermissionTypeWrite},
+	}
+
+	err := executeCommandWithConfig(t, fb, templateConfig, "permission", "template", "apply", "-b", "--strategy", "mirror", "backend", "myworkspace", "target")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"myworkspace/target": {
+			"Update: group developer READ => WRITE",
+			"Add: user {1111} (ADMIN)",
+			"Remove: user bot-ci (WRITE)",
+		},
+	}, fb.operations)
+
+	err = executeCommandWithConfig(t, fb, templateConfig, "permission", "template", "apply", "-b", "--strategy", "all", "backend", "myworkspace", "target")
+	assert.Error(t, err)
+}
+
+func TestTemplateApplyCmd_mixedCase(t *testing.T) {
+	fb := newFakeBackend()
+
+	err := executeCommandWithConfig(t, fb, templateConfig, "permission", "template", "apply", "-b", "mixed-case", "myworkspace", "target")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"myworkspace/target": {
+			"Add: group DevOps (READ)",
+		},
+	}, fb.operations)
+}
+
+func TestTemplateApplyCmd_invalid(t *testing.T) {
+	fb := newFakeBackend()
+
+	err := executeCommandWithConfig(t, fb, templateConfig, "permission", "template", "apply", "-b", "broken", "myworkspace", "target")
+	assert.EqualError(t, err, `template broken: invalid permission "owner" for group developer`)
+
+	err = executeCommandWithConfig(t, fb, templateConfig, "permission", "template", "apply", "-b", "unknown", "myworkspace", "target")
+	assert.EqualError(t, err, "template unknown is not defined")
+
+	err = executeCommandWithConfig(t, fb, templateConfig, "permission", "template", "apply", "-b", "legacy", "myworkspace", "target")
+	assert.ErrorContains(t, err, "[[templates.legacy.permissions]]")
+	assert.Empty(t, fb.operations)
+}
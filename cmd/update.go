@@ -1,9 +1,7 @@
 package cmd
 
 import (
-	"context"
 	"fmt"
-	"net/http"
 
 	"github.com/ikorihn/bbdan/api"
 	"github.com/spf13/cobra"
@@ -19,59 +17,92 @@ var updateCmd = &cobra.Command{
 		repository := args[1]
 		fmt.Printf("Update selected permissions of %s/%s\n", workspace, repository)
 
-		hc := http.DefaultClient
-
-		ba := api.NewBitbucketApi(hc, username, password)
-		ctx := context.Background()
-		permissions, err := ba.ListPermission(ctx, workspace, repository)
+		filter, err := getFilter(cmd)
 		if err != nil {
-			fmt.Printf("%v", err)
 			return err
 		}
 
-		selectedPermissions, err := askPermissionToUpdate(permissions)
+		ba, err := newBackend()
 		if err != nil {
 			return err
 		}
-		operation, err := askOperationType()
+		ctx := cmd.Context()
+		permissions, err := ba.ListPermission(ctx, workspace, repository)
 		if err != nil {
+			fmt.Printf("%v", err)
 			return err
 		}
 
-		var permission api.PermissionType
-		if operation == api.OperationTypeAdd || operation == api.OperationTypeUpdate {
-			permission, err = askPermissionType()
-			if err != nil {
-				return err
-			}
+		var operations []api.Operation
+		if principals := getPrincipals(cmd); len(principals) > 0 {
+			operations, err = updateOperationsFromFlags(cmd, permissions, filter, principals)
+		} else {
+			operations, err = askUpdateOperations(api.FilterPermissions(permissions, filter))
 		}
-
-		operations := []api.Operation{}
-		for _, v := range selectedPermissions {
-			var o api.Operation
-			switch operation {
-			case api.OperationTypeUpdate:
-				o = api.NewUpdateOperation(v, permission)
-			case api.OperationTypeRemove:
-				o = api.NewRemoveOperation(v)
-			default:
-				continue
-			}
-			operations = append(operations, o)
+		if err != nil {
+			return err
 		}
 
+		for _, v := range operations {
+			fmt.Println(v.Message())
+		}
 		err = ba.UpdatePermissions(ctx, workspace, repository, operations)
 		if err != nil {
-			fmt.Printf("Failed to update: %v\n", err)
+			printUpdateError[api.Operation](err)
 			return err
 		}
 
-		showPermissions(ba, workspace, repository)
+		showPermissions(ctx, ba, workspace, repository)
 
 		return nil
 	},
 }
 
+// updateOperationsFromFlags makes operations to give the permission of --permission to principals specified by flags.
+// Principals without permission are added.
+func updateOperationsFromFlags(cmd *cobra.Command, permissions []api.Permission, filter api.Filter, principals []api.Permission) ([]api.Operation, error) {
+	p, _ := cmd.Flags().GetString("permission")
+	permission, err := api.ParsePermissionType(p)
+	if err != nil {
+		return nil, err
+	}
+	return grantOperations(permissions, filter, principals, permission)
+}
+
+// askUpdateOperations asks permissions and operation to apply them.
+func askUpdateOperations(permissions []api.Permission) ([]api.Operation, error) {
+	selectedPermissions, err := askPermissionToUpdate(permissions)
+	if err != nil {
+		return nil, err
+	}
+	operation, err := askOperationType()
+	if err != nil {
+		return nil, err
+	}
+
+	var permission api.PermissionType
+	if operation == api.OperationTypeUpdate {
+		permission, err = askPermissionType()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	operations := []api.Operation{}
+	for _, v := range selectedPermissions {
+		if operation == api.OperationTypeRemove {
+			operations = append(operations, api.NewRemoveOperation(v))
+		} else {
+			operations = append(operations, api.NewUpdateOperation(v, permission))
+		}
+	}
+
+	return operations, nil
+}
+
 func init() {
 	permissionCmd.AddCommand(updateCmd)
+	addFilterFlags(updateCmd)
+	addPrincipalFlags(updateCmd)
+	updateCmd.Flags().StringP("permission", "p", "", "With --user or --group, permission to update or add: read|write|admin")
 }
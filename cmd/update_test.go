@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/ikorihn/bbdan/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateCmd_principals(t *testing.T) {
+	fb := newFakeBackend()
+	fb.permissions["myworkspace/myrepository"] = []api.Permission{
+		{ObjectId: "developer", ObjectName: "developer", ObjectType: api.ObjectTypeGroup, PermissionType: api.PermissionTypeWrite},
+		{ObjectId: "{1111}", ObjectName: "john-doe", ObjectType: api.ObjectTypeUser, PermissionType: api.PermissionTypeRead},
+		{ObjectId: "{2222}", ObjectName: "jane-doe", ObjectType: api.ObjectTypeUser, PermissionType: api.PermissionTypeAdmin},
+	}
+
+	err := executeCommand(t, fb, "permission", "update", "myworkspace", "myrepository",
+		"--user", "john-doe", "--user", "{2222}", "--group", "developer", "--permission", "admin")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"myworkspace/myrepository": {
+			"Update: user john-doe READ => ADMIN",
+			"Update: group developer WRITE => ADMIN",
+		},
+	}, fb.operations)
+}
+
+func TestUpdateCmd_principalsWithoutPermission(t *testing.T) {
+	fb := newFakeBackend()
+	fb.permissions["myworkspace/myrepository"] = []api.Permission{
+		{ObjectId: "{1111}", ObjectName: "john-doe", ObjectType: api.ObjectTypeUser, PermissionType: api.PermissionTypeRead},
+	}
+
+	err := executeCommand(t, fb, "permission", "update", "myworkspace", "myrepository", "--group", "developer", "--permission", "owner")
+	assert.Error(t, err)
+	assert.Empty(t, fb.operations)
+
+	err = executeCommand(t, fb, "permission", "update", "myworkspace", "myrepository", "--user", "{2222}", "--group", "developer", "--permission", "write")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"myworkspace/myrepository": {
+			"Add: user {2222} (WRITE)",
+			"Add: group developer (WRITE)",
+		},
+	}, fb.operations)
+}
+
+func TestUpdateCmd_principalsWithFilter(t *testing.T) {
+	fb := newFakeBackend()
+	fb.permissions["myworkspace/myrepository"] = []api.Permission{
+		{ObjectId: "developer", ObjectName: "developer", ObjectType: api.ObjectTypeGroup, PermissionType: api.PermissionTypeWrite},
+		{ObjectId: "{1111}", ObjectName: "john-doe", ObjectType: api.ObjectTypeUser, PermissionType: api.PermissionTypeRead},
+	}
+
+	err := executeCommand(t, fb, "permission", "update", "myworkspace", "myrepository",
+		"--exclude", "john-*", "--user", "john-doe", "--permission", "admin")
+	assert.EqualError(t, err, "user john-doe is excluded by filter")
+	assert.Empty(t, fb.operations)
+
+	err = executeCommand(t, fb, "permission", "update", "myworkspace", "myrepository",
+		"--include", "dev*", "--group", "developer", "--permission", "admin")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"myworkspace/myrepository": {
+			"Update: group developer WRITE => ADMIN",
+		},
+	}, fb.operations)
+}
@@ -0,0 +1,388 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ikorihn/bbdan/api"
+	"github.com/spf13/cobra"
+)
+
+// webhookCmd represents the webhook command
+var webhookCmd = &cobra.Command{
+	Use:   "webhook",
+	Short: "List, create, update, delete, copy webhooks of repository",
+	Long: `List, create, update, delete, copy webhooks of repository.
+
+Webhooks are identified by URL. Bitbucket never returns secrets of webhooks,
+so secrets are read from the file given by --secret-file.`,
+}
+
+// listWebhookCmd represents the webhook list command
+var listWebhookCmd = &cobra.Command{
+	Use:   "list workspace repository",
+	Short: "List webhooks of a repository",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		workspace := args[0]
+		repository := args[1]
+		output, _ := cmd.Flags().GetString("output")
+
+		wb, err := newWebhookBackend()
+		if err != nil {
+			return err
+		}
+
+		webhooks, err := wb.ListWebhooks(cmd.Context(), workspace, repository)
+		if err != nil {
+			return err
+		}
+
+		switch output {
+		case "json":
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(webhooks)
+		case "", "text":
+			fmt.Printf("List webhooks for %s/%s\n", workspace, repository)
+			printWebhooks(webhooks)
+			return nil
+		default:
+			return fmt.Errorf("invalid output %q: must be text or json", output)
+		}
+	},
+}
+
+// createWebhookCmd represents the webhook create command
+var createWebhookCmd = &cobra.Command{
+	Use:   "create workspace repository...",
+	Short: "Create a webhook in repositories",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		workspace := args[0]
+		repositories := args[1:]
+		webhookUrl, _ := cmd.Flags().GetString("url")
+		events, _ := cmd.Flags().GetStringSlice("event")
+		description, _ := cmd.Flags().GetString("description")
+		inactive, _ := cmd.Flags().GetBool("inactive")
+
+		if webhookUrl == "" {
+			return errors.New("--url is required")
+		}
+		if len(events) == 0 {
+			return errors.New("--event is required")
+		}
+		secret, err := readWebhookSecret(cmd)
+		if err != nil {
+			return err
+		}
+
+		wb, err := newWebhookBackend()
+		if err != nil {
+			return err
+		}
+
+		webhook := api.Webhook{
+			Url:         webhookUrl,
+			Description: description,
+			Active:      !inactive,
+			Events:      events,
+			SecretSet:   secret != "",
+			Secret:      secret,
+		}
+		for _, repository := range repositories {
+			fmt.Printf("Create webhook %s in %s/%s\n", webhookUrl, workspace, repository)
+
+			webhooks, err := wb.ListWebhooks(cmd.Context(), workspace, repository)
+			if err != nil {
+				return err
+			}
+			if _, ok := findWebhook(webhooks, webhookUrl); ok {
+				return fmt.Errorf("webhook %s already exists in %s/%s: use update", webhookUrl, workspace, repository)
+			}
+
+			operations := []api.WebhookOperation{api.NewAddWebhookOperation(webhook)}
+			if err := updateWebhooks(cmd, wb, workspace, repository, operations); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+// updateWebhookCmd represents the webhook update command
+var updateWebhookCmd = &cobra.Command{
+	Use:   "update workspace repository...",
+	Short: "Update a webhook of repositories",
+	Long:  "Update the webhook of the URL in repositories. Only the given flags are changed",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		workspace := args[0]
+		repositories := args[1:]
+		webhookUrl, _ := cmd.Flags().GetString("url")
+		events, _ := cmd.Flags().GetStringSlice("event")
+		description, _ := cmd.Flags().GetString("description")
+		active, _ := cmd.Flags().GetBool("active")
+
+		if webhookUrl == "" {
+			return errors.New("--url is required")
+		}
+		secret, err := readWebhookSecret(cmd)
+		if err != nil {
+			return err
+		}
+
+		wb, err := newWebhookBackend()
+		if err != nil {
+			return err
+		}
+
+		for _, repository := range repositories {
+			fmt.Printf("Update webhook %s of %s/%s\n", webhookUrl, workspace, repository)
+
+			webhooks, err := wb.ListWebhooks(cmd.Context(), workspace, repository)
+			if err != nil {
+				return err
+			}
+			current, ok := findWebhook(webhooks, webhookUrl)
+			if !ok {
+				return fmt.Errorf("webhook %s not found in %s/%s", webhookUrl, workspace, repository)
+			}
+
+			after := current
+			if cmd.Flags().Changed("event") {
+				after.Events = events
+			}
+			if cmd.Flags().Changed("description") {
+				after.Description = description
+			}
+			if cmd.Flags().Changed("active") {
+				after.Active = active
+			}
+			operation := api.NewUpdateWebhookOperation(current, after)
+			if secret != "" {
+				operation = operation.WithSecret(secret)
+			}
+			if operation.Same() {
+				fmt.Println(operation.Message())
+				continue
+			}
+
+			if err := updateWebhooks(cmd, wb, workspace, repository, []api.WebhookOperation{operation}); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+// deleteWebhookCmd represents the webhook delete command
+var deleteWebhookCmd = &cobra.Command{
+	Use:   "delete workspace repository...",
+	Short: "Delete a webhook of repositories",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		workspace := args[0]
+		repositories := args[1:]
+		webhookUrl, _ := cmd.Flags().GetString("url")
+		yes, _ := cmd.Flags().GetBool("yes")
+
+		if webhookUrl == "" {
+			return errors.New("--url is required")
+		}
+
+		wb, err := newWebhookBackend()
+		if err != nil {
+			return err
+		}
+
+		for _, repository := range repositories {
+			fmt.Printf("Delete webhook %s of %s/%s\n", webhookUrl, workspace, repository)
+
+			webhooks, err := wb.ListWebhooks(cmd.Context(), workspace, repository)
+			if err != nil {
+				return err
+			}
+			current, ok := findWebhook(webhooks, webhookUrl)
+			if !ok {
+				fmt.Printf("Webhook %s not found\n", webhookUrl)
+				continue
+			}
+
+			operations, err := selectOperations([]api.WebhookOperation{api.NewRemoveWebhookOperation(current)}, yes)
+			if err != nil {
+				return err
+			}
+			if err := updateWebhooks(cmd, wb, workspace, repository, operations); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+// copyWebhookCmd represents the webhook copy command
+var copyWebhookCmd = &cobra.Command{
+	Use:   "copy workspace source-repository target-repository",
+	Short: "Copy webhooks between repositories",
+	Long: `Make webhooks of the target repository the same as the source. Webhooks are matched by URL.
+
+Secrets of the source can't be read, so the secret of --secret-file is set
+to webhooks whose source has a secret and the target doesn't.`,
+	Args: cobra.ExactArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		workspace := args[0]
+		srcRepository := args[1]
+		targetRepository := args[2]
+		batch, _ := cmd.Flags().GetBool("batch")
+
+		fmt.Printf("Copy webhooks from %s/%s to %s/%s\n", workspace, srcRepository, workspace, targetRepository)
+
+		secret, err := readWebhookSecret(cmd)
+		if err != nil {
+			return err
+		}
+
+		wb, err := newWebhookBackend()
+		if err != nil {
+			return err
+		}
+
+		ctx := cmd.Context()
+
+		srcWebhooks, err := wb.ListWebhooks(ctx, workspace, srcRepository)
+		if err != nil {
+			return err
+		}
+		targetWebhooks, err := wb.ListWebhooks(ctx, workspace, targetRepository)
+		if err != nil {
+			return err
+		}
+
+		// Secrets are checked before prompts, so that choices aren't thrown away for a missing --secret-file.
+		operations := api.MakeWebhookOperationList(srcWebhooks, targetWebhooks)
+		missing := make([]string, 0)
+		for i, v := range operations {
+			if !v.NeedsSecret() {
+				continue
+			}
+			if secret == "" {
+				missing = append(missing, v.Webhook().Url)
+				continue
+			}
+			operations[i] = v.WithSecret(secret)
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("webhooks need a secret, give it with --secret-file: %s", strings.Join(missing, ", "))
+		}
+
+		selectedOperations, err := selectOperations(operations, batch)
+		if err != nil {
+			return err
+		}
+
+		return updateWebhooks(cmd, wb, workspace, targetRepository, selectedOperations)
+	},
+}
+
+// newWebhookBackend returns the backend if it supports webhooks.
+func newWebhookBackend() (api.WebhookBackend, error) {
+	ba, err := newBackend()
+	if err != nil {
+		return nil, err
+	}
+	wb, ok := ba.(api.WebhookBackend)
+	if !ok {
+		return nil, errors.New("webhooks are supported only by Bitbucket Cloud")
+	}
+	return wb, nil
+}
+
+// updateWebhooks applies operations to the repository and shows its webhooks.
+func updateWebhooks(cmd *cobra.Command, wb api.WebhookBackend, workspace, repository string, operations []api.WebhookOperation) error {
+	err := wb.UpdateWebhooks(cmd.Context(), workspace, repository, operations)
+	if err != nil {
+		printUpdateError[api.WebhookOperation](err)
+		return err
+	}
+
+	webhooks, err := wb.ListWebhooks(cmd.Context(), workspace, repository)
+	if err != nil {
+		return err
+	}
+	printWebhooks(webhooks)
+	return nil
+}
+
+func findWebhook(webhooks []api.Webhook, webhookUrl string) (api.Webhook, bool) {
+	for _, v := range webhooks {
+		if v.Url == webhookUrl {
+			return v, true
+		}
+	}
+	return api.Webhook{}, false
+}
+
+// readWebhookSecret reads the secret from the file of --secret-file, without the trailing newline.
+func readWebhookSecret(cmd *cobra.Command) (string, error) {
+	file, _ := cmd.Flags().GetString("secret-file")
+	if file == "" {
+		return "", nil
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	secret := strings.TrimRight(string(b), "\r\n")
+	if secret == "" {
+		return "", fmt.Errorf("secret file %s is empty", file)
+	}
+	return secret, nil
+}
+
+func printWebhooks(webhooks []api.Webhook) {
+	fmt.Println("==== RESULT ====")
+	fmt.Println("uuid, url, active, events, secret, description")
+	for _, v := range webhooks {
+		fmt.Printf("%s, %s, %t, %s, %t, %s\n",
+			v.Uuid,
+			v.Url,
+			v.Active,
+			strings.Join(v.Events, "|"),
+			v.SecretSet,
+			v.Description,
+		)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(webhookCmd)
+	webhookCmd.AddCommand(listWebhookCmd)
+	webhookCmd.AddCommand(createWebhookCmd)
+	webhookCmd.AddCommand(updateWebhookCmd)
+	webhookCmd.AddCommand(deleteWebhookCmd)
+	webhookCmd.AddCommand(copyWebhookCmd)
+
+	listWebhookCmd.Flags().StringP("output", "o", "text", "Output format: text|json")
+
+	createWebhookCmd.Flags().String("url", "", "URL of the webhook")
+	createWebhookCmd.Flags().StringSlice("event", nil, "Events to trigger the webhook, e.g. repo:push,pullrequest:created")
+	createWebhookCmd.Flags().String("description", "", "Description of the webhook")
+	createWebhookCmd.Flags().Bool("inactive", false, "Create the webhook inactive")
+	createWebhookCmd.Flags().String("secret-file", "", "File of the secret to sign requests of the webhook")
+
+	updateWebhookCmd.Flags().String("url", "", "URL of the webhook to update")
+	updateWebhookCmd.Flags().StringSlice("event", nil, "Events to trigger the webhook, e.g. repo:push,pullrequest:created")
+	updateWebhookCmd.Flags().String("description", "", "Description of the webhook")
+	updateWebhookCmd.Flags().Bool("active", true, "Whether the webhook is active")
+	updateWebhookCmd.Flags().String("secret-file", "", "File of the new secret of the webhook")
+
+	deleteWebhookCmd.Flags().String("url", "", "URL of the webhook to delete")
+	deleteWebhookCmd.Flags().BoolP("yes", "y", false, "Delete without asking")
+
+	copyWebhookCmd.Flags().BoolP("batch", "b", false, "Execute in batch mode. Copy all without asking")
+	copyWebhookCmd.Flags().String("secret-file", "", "File of the secret set to webhooks which need one")
+}
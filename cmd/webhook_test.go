@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ikorihn/bbdan/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func newWebhookFakeBackend() *fakeBackend {
+	fb := newFakeBackend()
+	fb.webhooks["myworkspace/src"] = []api.Webhook{
+		{Uuid: "{1}", Url: "https://ci.example.com/hook", Active: true, Events: []string{"repo:push"}, SecretSet: true},
+		{Uuid: "{2}", Url: "https://chat.example.com/hook", Active: true, Events: []string{"repo:push"}},
+	}
+	fb.webhooks["myworkspace/target"] = []api.Webhook{
+		{Uuid: "{12}", Url: "https://chat.example.com/hook", Active: false, Events: []string{"repo:push"}},
+		{Uuid: "{13}", Url: "https://old.example.com/hook", Active: true, Events: []string{"repo:push"}},
+	}
+	return fb
+}
+
+func writeSecretFile(t *testing.T) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "secret")
+	err := os.WriteFile(file, []byte("s3cret\n"), 0o600)
+	assert.NoError(t, err)
+	return file
+}
+
+func TestWebhookCopyCmd(t *testing.T) {
+	fb := newWebhookFakeBackend()
+	err := executeCommand(t, fb, "webhook", "copy", "-b", "myworkspace", "src", "target")
+	assert.ErrorContains(t, err, "https://ci.example.com/hook")
+	assert.Empty(t, fb.webhookOperations)
+
+	err = executeCommand(t, fb, "webhook", "copy", "-b", "--secret-file", writeSecretFile(t), "myworkspace", "src", "target")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"myworkspace/target": {
+			"Update: https://chat.example.com/hook (inactive events=repo:push) => (active events=repo:push)",
+			"Add: https://ci.example.com/hook (active events=repo:push secret=set)",
+			"Remove: https://old.example.com/hook (active events=repo:push)",
+		},
+	}, fb.webhookOperations)
+}
+
+func TestWebhookCreateCmd(t *testing.T) {
+	fb := newWebhookFakeBackend()
+	err := executeCommand(t, fb, "webhook", "create", "--url", "https://ci.example.com/hook", "--event", "repo:push,pullrequest:created", "myworkspace", "target", "other")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"myworkspace/target": {"Add: https://ci.example.com/hook (active events=pullrequest:created|repo:push)"},
+		"myworkspace/other":  {"Add: https://ci.example.com/hook (active events=pullrequest:created|repo:push)"},
+	}, fb.webhookOperations)
+
+	err = executeCommand(t, fb, "webhook", "create", "--url", "https://ci.example.com/hook", "--event", "repo:push", "myworkspace", "src")
+	assert.ErrorContains(t, err, "already exists")
+}
+
+func TestWebhookUpdateCmd(t *testing.T) {
+	fb := newWebhookFakeBackend()
+	err := executeCommand(t, fb, "webhook", "update", "--url", "https://chat.example.com/hook", "--active", "myworkspace", "src", "target")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"myworkspace/target": {"Update: https://chat.example.com/hook (inactive events=repo:push) => (active events=repo:push)"},
+	}, fb.webhookOperations)
+
+	err = executeCommand(t, fb, "webhook", "update", "--url", "https://none.example.com/hook", "--active", "myworkspace", "src")
+	assert.ErrorContains(t, err, "not found")
+}
+
+func TestWebhookDeleteCmd(t *testing.T) {
+	fb := newWebhookFakeBackend()
+	err := executeCommand(t, fb, "webhook", "delete", "-y", "--url", "https://chat.example.com/hook", "myworkspace", "src", "target")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"myworkspace/src":    {"Remove: https://chat.example.com/hook (active events=repo:push)"},
+		"myworkspace/target": {"Remove: https://chat.example.com/hook (inactive events=repo:push)"},
+	}, fb.webhookOperations)
+}
@@ -0,0 +1,853 @@
+// Package mockserver is an in-memory imitation of Bitbucket Cloud REST API 2.0
+// serving the endpoints bbdan uses. Changes by PUT and DELETE are kept while the server runs.
+package mockserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultPagelen is the page size used when the request does not specify pagelen.
+const DefaultPagelen = 10
+
+// Fixture is the initial state of the server.
+type Fixture struct {
+	// CurrentUser is the UUID of the user returned by /user. Defaults to the first user.
+	CurrentUser string               `json:"current_user"`
+	Users       []User               `json:"users"`
+	Workspaces  map[string]Workspace `json:"workspaces"`
+}
+
+type User struct {
+	Uuid        string `json:"uuid"`
+	AccountId   string `json:"account_id"`
+	Nickname    string `json:"nickname"`
+	DisplayName string `json:"display_name"`
+}
+
+type Group struct {
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+}
+
+type Workspace struct {
+	Groups       []Group               `json:"groups"`
+	Repositories map[string]Repository `json:"repositories"`
+}
+
+// Repository has permissions by user UUID and group slug, and default reviewers by user UUID.
+type Repository struct {
+	Users              map[string]string   `json:"users"`
+	Groups             map[string]string   `json:"groups"`
+	DefaultReviewers   []string            `json:"default_reviewers"`
+	BranchRestrictions []BranchRestriction `json:"branch_restrictions"`
+	BranchingModel     *BranchingModel     `json:"branching_model,omitempty"`
+	Settings           *RepositorySettings `json:"settings,omitempty"`
+	Webhooks           []Webhook           `json:"webhooks"`
+}
+
+// Webhook has its secret in plain text, which is never returned by the API.
+type Webhook struct {
+	Uuid        string   `json:"uuid"`
+	Url         string   `json:"url"`
+	Description string   `json:"description"`
+	Active      bool     `json:"active"`
+	Events      []string `json:"events"`
+	Secret      string   `json:"secret,omitempty"`
+}
+
+// RepositorySettings is the properties of a repository.
+// Repositories without it are private and have the main branch main.
+type RepositorySettings struct {
+	IsPrivate   bool   `json:"is_private"`
+	ForkPolicy  string `json:"fork_policy"`
+	MainBranch  string `json:"mainbranch"`
+	Language    string `json:"language"`
+	Description string `json:"description"`
+	HasIssues   bool   `json:"has_issues"`
+	HasWiki     bool   `json:"has_wiki"`
+}
+
+func defaultRepositorySettings() *RepositorySettings {
+	return &RepositorySettings{IsPrivate: true, ForkPolicy: "no_public_forks", MainBranch: "main"}
+}
+
+// BranchRestriction has exempted users by UUID and groups by slug.
+type BranchRestriction struct {
+	Id              int      `json:"id"`
+	Kind            string   `json:"kind"`
+	BranchMatchKind string   `json:"branch_match_kind"`
+	BranchType      string   `json:"branch_type,omitempty"`
+	Pattern         string   `json:"pattern"`
+	Value           *int     `json:"value,omitempty"`
+	Users           []string `json:"users"`
+	Groups          []string `json:"groups"`
+}
+
+// BranchingModel is the branching model settings in the format of the API.
+// Repositories without it have the default settings of Bitbucket.
+type BranchingModel struct {
+	Development BranchingModelBranch `json:"development"`
+	Production  BranchingModelBranch `json:"production"`
+	BranchTypes []BranchType         `json:"branch_types"`
+}
+
+type BranchingModelBranch struct {
+	Name          string `json:"name"`
+	UseMainBranch bool   `json:"use_mainbranch"`
+	Enabled       bool   `json:"enabled"`
+}
+
+type BranchType struct {
+	Kind    string `json:"kind"`
+	Enabled bool   `json:"enabled"`
+	Prefix  string `json:"prefix,omitempty"`
+}
+
+func defaultBranchingModel() *BranchingModel {
+	return &BranchingModel{
+		Development: BranchingModelBranch{UseMainBranch: true},
+		Production:  BranchingModelBranch{UseMainBranch: true},
+		BranchTypes: []BranchType{
+			{Kind: "feature", Enabled: true, Prefix: "feature/"},
+			{Kind: "bugfix", Enabled: true, Prefix: "bugfix/"},
+			{Kind: "release", Enabled: true, Prefix: "release/"},
+			{Kind: "hotfix", Enabled: true, Prefix: "hotfix/"},
+		},
+	}
+}
+
+// LoadFixture reads a fixture from the JSON file.
+func LoadFixture(file string) (Fixture, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return Fixture{}, err
+	}
+	var f Fixture
+	if err := json.Unmarshal(b, &f); err != nil {
+		return Fixture{}, fmt.Errorf("invalid fixture %s: %w", file, err)
+	}
+	return f, nil
+}
+
+// Server is an http.Handler imitating Bitbucket Cloud.
+type Server struct {
+	mu      sync.Mutex
+	state   Fixture
+	pagelen int
+	// hookSeq numbers UUIDs of created webhooks
+	hookSeq int
+}
+
+// New creates a server seeded from the fixture. pagelen is the default page size.
+func New(fixture Fixture, pagelen int) *Server {
+	if pagelen <= 0 {
+		pagelen = DefaultPagelen
+	}
+	if fixture.Workspaces == nil {
+		fixture.Workspaces = map[string]Workspace{}
+	}
+	return &Server{
+		state:   fixture,
+		pagelen: pagelen,
+	}
+}
+
+type errorResponse struct {
+	Type  string `json:"type"`
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func writeError(w http.ResponseWriter, status int, format string, a ...any) {
+	var e errorResponse
+	e.Type = "error"
+	e.Error.Message = fmt.Sprintf(format, a...)
+	writeJSON(w, status, e)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// /user, /repositories/{ws}, /repositories/{ws}/{repo}/...
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "user" && r.Method == "GET":
+		s.getUser(w, r)
+	case len(parts) == 2 && parts[0] == "repositories" && r.Method == "GET":
+		s.listRepositories(w, r, parts[1])
+	case len(parts) >= 3 && parts[0] == "repositories":
+		ws, ok := s.state.Workspaces[parts[1]]
+		if !ok {
+			writeError(w, http.StatusNotFound, "workspace %s not found", parts[1])
+			return
+		}
+		repo, ok := ws.Repositories[parts[2]]
+		if !ok {
+			writeError(w, http.StatusNotFound, "repository %s/%s not found", parts[1], parts[2])
+			return
+		}
+		s.serveRepository(w, r, ws, &repo, parts[3:])
+		ws.Repositories[parts[2]] = repo
+	default:
+		writeError(w, http.StatusNotFound, "%s %s is not supported", r.Method, r.URL.Path)
+	}
+}
+
+func (s *Server) serveRepository(w http.ResponseWriter, r *http.Request, ws Workspace, repo *Repository, parts []string) {
+	switch {
+	case len(parts) == 0:
+		if repo.Settings == nil {
+			repo.Settings = defaultRepositorySettings()
+		}
+		switch r.Method {
+		case "GET":
+			writeJSON(w, http.StatusOK, repository(r, repo.Settings))
+		case "PUT":
+			if !updateRepositorySettings(w, r, repo.Settings) {
+				return
+			}
+			writeJSON(w, http.StatusOK, repository(r, repo.Settings))
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+		}
+
+	case len(parts) == 2 && parts[0] == "permissions-config" && r.Method == "GET":
+		switch parts[1] {
+		case "users":
+			s.listUserPermissions(w, r, repo)
+		case "groups":
+			s.listGroupPermissions(w, r, ws, repo)
+		default:
+			writeError(w, http.StatusNotFound, "%s not found", r.URL.Path)
+		}
+
+	case len(parts) == 3 && parts[0] == "permissions-config" && parts[1] == "users":
+		user, ok := s.findUser(parts[2])
+		if !ok {
+			writeError(w, http.StatusNotFound, "user %s not found", parts[2])
+			return
+		}
+		if repo.Users == nil {
+			repo.Users = map[string]string{}
+		}
+		s.updatePermission(w, r, repo.Users, user.Uuid, func(p string) any { return userPermission(user, p) })
+
+	case len(parts) == 3 && parts[0] == "permissions-config" && parts[1] == "groups":
+		group, ok := findGroup(ws, parts[2])
+		if !ok {
+			writeError(w, http.StatusNotFound, "group %s not found", parts[2])
+			return
+		}
+		if repo.Groups == nil {
+			repo.Groups = map[string]string{}
+		}
+		s.updatePermission(w, r, repo.Groups, group.Slug, func(p string) any { return groupPermission(group, p) })
+
+	case len(parts) == 1 && parts[0] == "default-reviewers" && r.Method == "GET":
+		values := make([]any, 0)
+		for _, v := range repo.DefaultReviewers {
+			if user, ok := s.findUser(v); ok {
+				values = append(values, bitbucketUser(user))
+			}
+		}
+		s.writePage(w, r, values)
+
+	case len(parts) == 2 && parts[0] == "default-reviewers":
+		user, ok := s.findUser(parts[1])
+		if !ok {
+			writeError(w, http.StatusNotFound, "user %s not found", parts[1])
+			return
+		}
+		rest := make([]string, 0)
+		for _, v := range repo.DefaultReviewers {
+			if v != user.Uuid {
+				rest = append(rest, v)
+			}
+		}
+		switch r.Method {
+		case "PUT":
+			repo.DefaultReviewers = append(rest, user.Uuid)
+			writeJSON(w, http.StatusOK, bitbucketUser(user))
+		case "DELETE":
+			repo.DefaultReviewers = rest
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+		}
+
+	case len(parts) == 1 && parts[0] == "branch-restrictions":
+		switch r.Method {
+		case "GET":
+			values := make([]any, 0)
+			for _, v := range repo.BranchRestrictions {
+				values = append(values, s.branchRestriction(ws, v))
+			}
+			s.writePage(w, r, values)
+		case "POST":
+			br, ok := readBranchRestriction(w, r)
+			if !ok {
+				return
+			}
+			br.Id = 1
+			for _, v := range repo.BranchRestrictions {
+				if v.Id >= br.Id {
+					br.Id = v.Id + 1
+				}
+			}
+			repo.BranchRestrictions = append(repo.BranchRestrictions, br)
+			writeJSON(w, http.StatusCreated, s.branchRestriction(ws, br))
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+		}
+
+	case len(parts) == 2 && parts[0] == "branch-restrictions":
+		id, _ := strconv.Atoi(parts[1])
+		i := -1
+		for j, v := range repo.BranchRestrictions {
+			if v.Id == id {
+				i = j
+			}
+		}
+		if i < 0 {
+			writeError(w, http.StatusNotFound, "branch restriction %s not found", parts[1])
+			return
+		}
+		switch r.Method {
+		case "GET":
+			writeJSON(w, http.StatusOK, s.branchRestriction(ws, repo.BranchRestrictions[i]))
+		case "PUT":
+			br, ok := readBranchRestriction(w, r)
+			if !ok {
+				return
+			}
+			br.Id = id
+			repo.BranchRestrictions[i] = br
+			writeJSON(w, http.StatusOK, s.branchRestriction(ws, br))
+		case "DELETE":
+			repo.BranchRestrictions = append(repo.BranchRestrictions[:i:i], repo.BranchRestrictions[i+1:]...)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+		}
+
+	case len(parts) == 1 && parts[0] == "hooks":
+		switch r.Method {
+		case "GET":
+			values := make([]any, 0)
+			for _, v := range repo.Webhooks {
+				values = append(values, webhook(v))
+			}
+			s.writePage(w, r, values)
+		case "POST":
+			hook := Webhook{Active: true}
+			if !readWebhook(w, r, &hook) {
+				return
+			}
+			s.hookSeq++
+			hook.Uuid = fmt.Sprintf("{hook-%d}", s.hookSeq)
+			repo.Webhooks = append(repo.Webhooks, hook)
+			writeJSON(w, http.StatusCreated, webhook(hook))
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+		}
+
+	case len(parts) == 2 && parts[0] == "hooks":
+		i := -1
+		for j, v := range repo.Webhooks {
+			if v.Uuid == parts[1] {
+				i = j
+			}
+		}
+		if i < 0 {
+			writeError(w, http.StatusNotFound, "webhook %s not found", parts[1])
+			return
+		}
+		switch r.Method {
+		case "GET":
+			writeJSON(w, http.StatusOK, webhook(repo.Webhooks[i]))
+		case "PUT":
+			hook := repo.Webhooks[i]
+			if !readWebhook(w, r, &hook) {
+				return
+			}
+			repo.Webhooks[i] = hook
+			writeJSON(w, http.StatusOK, webhook(hook))
+		case "DELETE":
+			repo.Webhooks = append(repo.Webhooks[:i:i], repo.Webhooks[i+1:]...)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+		}
+
+	case len(parts) == 2 && parts[0] == "branching-model" && parts[1] == "settings":
+		if repo.BranchingModel == nil {
+			repo.BranchingModel = defaultBranchingModel()
+		}
+		switch r.Method {
+		case "GET":
+			writeJSON(w, http.StatusOK, repo.BranchingModel)
+		case "PUT":
+			if !updateBranchingModel(w, r, repo.BranchingModel) {
+				return
+			}
+			writeJSON(w, http.StatusOK, repo.BranchingModel)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+		}
+
+	default:
+		writeError(w, http.StatusNotFound, "%s %s is not supported", r.Method, r.URL.Path)
+	}
+}
+
+// readWebhook changes the webhook by the body of POST and PUT.
+// The secret is kept if it is not in the body, and removed if it is null.
+func readWebhook(w http.ResponseWriter, r *http.Request, hook *Webhook) bool {
+	b, _ := io.ReadAll(r.Body)
+	var body struct {
+		Url         *string         `json:"url"`
+		Description *string         `json:"description"`
+		Active      *bool           `json:"active"`
+		Events      []string        `json:"events"`
+		Secret      json.RawMessage `json:"secret"`
+	}
+	if err := json.Unmarshal(b, &body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid body: %v", err)
+		return false
+	}
+
+	if body.Url != nil {
+		hook.Url = *body.Url
+	}
+	if body.Description != nil {
+		hook.Description = *body.Description
+	}
+	if body.Active != nil {
+		hook.Active = *body.Active
+	}
+	if body.Events != nil {
+		hook.Events = body.Events
+	}
+	if body.Secret != nil {
+		var secret *string
+		if err := json.Unmarshal(body.Secret, &secret); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid secret: %v", err)
+			return false
+		}
+		hook.Secret = ""
+		if secret != nil {
+			hook.Secret = *secret
+		}
+	}
+	if hook.Url == "" || len(hook.Events) == 0 {
+		writeError(w, http.StatusBadRequest, "url and events are required")
+		return false
+	}
+	return true
+}
+
+func webhook(hook Webhook) map[string]any {
+	return map[string]any{
+		"type":        "webhook_subscription",
+		"uuid":        hook.Uuid,
+		"url":         hook.Url,
+		"description": hook.Description,
+		"active":      hook.Active,
+		"events":      hook.Events,
+		"secret_set":  hook.Secret != "",
+	}
+}
+
+// repository renders the repository of the path of the request.
+func repository(r *http.Request, settings *RepositorySettings) map[string]any {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	return map[string]any{
+		"type":        "repository",
+		"slug":        parts[2],
+		"name":        parts[2],
+		"full_name":   parts[1] + "/" + parts[2],
+		"is_private":  settings.IsPrivate,
+		"fork_policy": settings.ForkPolicy,
+		"mainbranch":  map[string]any{"type": "branch", "name": settings.MainBranch},
+		"language":    settings.Language,
+		"description": settings.Description,
+		"has_issues":  settings.HasIssues,
+		"has_wiki":    settings.HasWiki,
+	}
+}
+
+// updateRepositorySettings changes only the fields in the body of PUT, as the API does.
+func updateRepositorySettings(w http.ResponseWriter, r *http.Request, settings *RepositorySettings) bool {
+	b, _ := io.ReadAll(r.Body)
+	var body struct {
+		IsPrivate  *bool   `json:"is_private"`
+		ForkPolicy *string `json:"fork_policy"`
+		MainBranch *struct {
+			Name string `json:"name"`
+		} `json:"mainbranch"`
+		Language    *string `json:"language"`
+		Description *string `json:"description"`
+		HasIssues   *bool   `json:"has_issues"`
+		HasWiki     *bool   `json:"has_wiki"`
+	}
+	if err := json.Unmarshal(b, &body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid body: %v", err)
+		return false
+	}
+
+	if body.IsPrivate != nil {
+		settings.IsPrivate = *body.IsPrivate
+	}
+	if body.ForkPolicy != nil {
+		settings.ForkPolicy = *body.ForkPolicy
+	}
+	if body.MainBranch != nil {
+		settings.MainBranch = body.MainBranch.Name
+	}
+	if body.Language != nil {
+		settings.Language = *body.Language
+	}
+	if body.Description != nil {
+		settings.Description = *body.Description
+	}
+	if body.HasIssues != nil {
+		settings.HasIssues = *body.HasIssues
+	}
+	if body.HasWiki != nil {
+		settings.HasWiki = *body.HasWiki
+	}
+	return true
+}
+
+// updateBranchingModel changes only the fields in the body of PUT, as the API does.
+func updateBranchingModel(w http.ResponseWriter, r *http.Request, bm *BranchingModel) bool {
+	type branch struct {
+		Name          *string `json:"name"`
+		UseMainBranch *bool   `json:"use_mainbranch"`
+		Enabled       *bool   `json:"enabled"`
+	}
+	b, _ := io.ReadAll(r.Body)
+	var body struct {
+		Development *branch `json:"development"`
+		Production  *branch `json:"production"`
+		BranchTypes []struct {
+			Kind    string  `json:"kind"`
+			Enabled *bool   `json:"enabled"`
+			Prefix  *string `json:"prefix"`
+		} `json:"branch_types"`
+	}
+	if err := json.Unmarshal(b, &body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid body: %v", err)
+		return false
+	}
+
+	for _, v := range []struct {
+		src    *branch
+		target *BranchingModelBranch
+	}{{body.Development, &bm.Development}, {body.Production, &bm.Production}} {
+		if v.src == nil {
+			continue
+		}
+		if v.src.Name != nil {
+			v.target.Name = *v.src.Name
+		}
+		if v.src.UseMainBranch != nil {
+			v.target.UseMainBranch = *v.src.UseMainBranch
+		}
+		if v.src.Enabled != nil {
+			v.target.Enabled = *v.src.Enabled
+		}
+	}
+
+	for _, v := range body.BranchTypes {
+		i := -1
+		for j, t := range bm.BranchTypes {
+			if t.Kind == v.Kind {
+				i = j
+			}
+		}
+		if i < 0 {
+			writeError(w, http.StatusBadRequest, "unknown branch type %q", v.Kind)
+			return false
+		}
+		if v.Enabled != nil {
+			bm.BranchTypes[i].Enabled = *v.Enabled
+		}
+		if v.Prefix != nil {
+			bm.BranchTypes[i].Prefix = *v.Prefix
+		}
+	}
+	return true
+}
+
+// readBranchRestriction reads the body of POST and PUT, where users and groups are objects with uuid and slug.
+func readBranchRestriction(w http.ResponseWriter, r *http.Request) (BranchRestriction, bool) {
+	b, _ := io.ReadAll(r.Body)
+	var body struct {
+		Kind            string `json:"kind"`
+		BranchMatchKind string `json:"branch_match_kind"`
+		BranchType      string `json:"branch_type"`
+		Pattern         string `json:"pattern"`
+		Value           *int   `json:"value"`
+		Users           []struct {
+			Uuid string `json:"uuid"`
+		} `json:"users"`
+		Groups []struct {
+			Slug string `json:"slug"`
+		} `json:"groups"`
+	}
+	if err := json.Unmarshal(b, &body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid body: %v", err)
+		return BranchRestriction{}, false
+	}
+	if body.Kind == "" {
+		writeError(w, http.StatusBadRequest, "kind is required")
+		return BranchRestriction{}, false
+	}
+
+	br := BranchRestriction{
+		Kind:            body.Kind,
+		BranchMatchKind: body.BranchMatchKind,
+		BranchType:      body.BranchType,
+		Pattern:         body.Pattern,
+		Value:           body.Value,
+		Users:           make([]string, 0),
+		Groups:          make([]string, 0),
+	}
+	for _, v := range body.Users {
+		br.Users = append(br.Users, v.Uuid)
+	}
+	for _, v := range body.Groups {
+		br.Groups = append(br.Groups, v.Slug)
+	}
+	return br, true
+}
+
+func (s *Server) branchRestriction(ws Workspace, br BranchRestriction) map[string]any {
+	users := make([]any, 0)
+	for _, v := range br.Users {
+		if user, ok := s.findUser(v); ok {
+			users = append(users, bitbucketUser(user))
+		}
+	}
+	groups := make([]any, 0)
+	for _, v := range br.Groups {
+		if group, ok := findGroup(ws, v); ok {
+			groups = append(groups, groupPermission(group, "")["group"])
+		}
+	}
+	res := map[string]any{
+		"type":              "branchrestriction",
+		"id":                br.Id,
+		"kind":              br.Kind,
+		"branch_match_kind": br.BranchMatchKind,
+		"pattern":           br.Pattern,
+		"users":             users,
+		"groups":            groups,
+	}
+	if br.BranchType != "" {
+		res["branch_type"] = br.BranchType
+	}
+	if br.Value != nil {
+		res["value"] = *br.Value
+	}
+	return res
+}
+
+func (s *Server) updatePermission(w http.ResponseWriter, r *http.Request, permissions map[string]string, id string, render func(string) any) {
+	switch r.Method {
+	case "PUT":
+		b, _ := io.ReadAll(r.Body)
+		var body struct {
+			Permission string `json:"permission"`
+		}
+		if err := json.Unmarshal(b, &body); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid body: %v", err)
+			return
+		}
+		switch body.Permission {
+		case "read", "write", "admin":
+		default:
+			writeError(w, http.StatusBadRequest, "invalid permission %q", body.Permission)
+			return
+		}
+		permissions[id] = body.Permission
+		writeJSON(w, http.StatusOK, render(body.Permission))
+	case "DELETE":
+		if _, ok := permissions[id]; !ok {
+			writeError(w, http.StatusNotFound, "%s has no permission", id)
+			return
+		}
+		delete(permissions, id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+	}
+}
+
+func (s *Server) getUser(w http.ResponseWriter, r *http.Request) {
+	if len(s.state.Users) == 0 {
+		writeError(w, http.StatusUnauthorized, "no users in fixture")
+		return
+	}
+	user := s.state.Users[0]
+	if u, ok := s.findUser(s.state.CurrentUser); ok {
+		user = u
+	}
+	w.Header().Set("X-OAuth-Scopes", "repository:admin")
+	writeJSON(w, http.StatusOK, bitbucketUser(user))
+}
+
+func (s *Server) listRepositories(w http.ResponseWriter, r *http.Request, workspace string) {
+	ws, ok := s.state.Workspaces[workspace]
+	if !ok {
+		writeError(w, http.StatusNotFound, "workspace %s not found", workspace)
+		return
+	}
+
+	values := make([]any, 0)
+	for _, slug := range sortedKeys(ws.Repositories) {
+		values = append(values, map[string]any{
+			"type":       "repository",
+			"slug":       slug,
+			"name":       slug,
+			"full_name":  workspace + "/" + slug,
+			"is_private": true,
+		})
+	}
+	s.writePage(w, r, values)
+}
+
+func (s *Server) listUserPermissions(w http.ResponseWriter, r *http.Request, repo *Repository) {
+	values := make([]any, 0)
+	for _, id := range sortedKeys(repo.Users) {
+		if user, ok := s.findUser(id); ok {
+			values = append(values, userPermission(user, repo.Users[id]))
+		}
+	}
+	s.writePage(w, r, values)
+}
+
+func (s *Server) listGroupPermissions(w http.ResponseWriter, r *http.Request, ws Workspace, repo *Repository) {
+	values := make([]any, 0)
+	for _, slug := range sortedKeys(repo.Groups) {
+		if group, ok := findGroup(ws, slug); ok {
+			values = append(values, groupPermission(group, repo.Groups[slug]))
+		}
+	}
+	s.writePage(w, r, values)
+}
+
+// writePage writes a page of values selected by page and pagelen of the query, with the next link.
+func (s *Server) writePage(w http.ResponseWriter, r *http.Request, values []any) {
+	q := r.URL.Query()
+	page, _ := strconv.Atoi(q.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	pagelen, _ := strconv.Atoi(q.Get("pagelen"))
+	if pagelen < 1 {
+		pagelen = s.pagelen
+	}
+
+	start := (page - 1) * pagelen
+	if start > len(values) {
+		start = len(values)
+	}
+	end := start + pagelen
+	if end > len(values) {
+		end = len(values)
+	}
+
+	res := map[string]any{
+		"values":  values[start:end],
+		"page":    page,
+		"pagelen": pagelen,
+		"size":    len(values),
+	}
+	if end < len(values) {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		q.Set("page", strconv.Itoa(page+1))
+		q.Set("pagelen", strconv.Itoa(pagelen))
+		res["next"] = fmt.Sprintf("%s://%s%s?%s", scheme, r.Host, r.URL.Path, q.Encode())
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// findUser finds a user by UUID, account id or nickname.
+func (s *Server) findUser(id string) (User, bool) {
+	for _, v := range s.state.Users {
+		if v.Uuid == id || v.AccountId == id || v.Nickname == id {
+			return v, true
+		}
+	}
+	return User{}, false
+}
+
+func findGroup(ws Workspace, slug string) (Group, bool) {
+	for _, v := range ws.Groups {
+		if v.Slug == slug {
+			return v, true
+		}
+	}
+	return Group{}, false
+}
+
+func bitbucketUser(u User) map[string]any {
+	return map[string]any{
+		"type":         "user",
+		"uuid":         u.Uuid,
+		"account_id":   u.AccountId,
+		"nickname":     u.Nickname,
+		"display_name": u.DisplayName,
+	}
+}
+
+func userPermission(u User, permission string) map[string]any {
+	return map[string]any{
+		"type":       "repository_user_permission",
+		"permission": permission,
+		"user":       bitbucketUser(u),
+	}
+}
+
+func groupPermission(g Group, permission string) map[string]any {
+	return map[string]any{
+		"type":       "repository_group_permission",
+		"permission": permission,
+		"group": map[string]any{
+			"type":      "group",
+			"slug":      g.Slug,
+			"name":      g.Name,
+			"full_slug": g.Slug,
+		},
+	}
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,206 @@
+package mockserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ikorihn/bbdan/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestApi(t *testing.T, pagelen int) *api.BitbucketApi {
+	t.Helper()
+
+	fixture, err := LoadFixture("testdata/fixture.json")
+	assert.NoError(t, err)
+
+	ts := httptest.NewServer(New(fixture, pagelen))
+	t.Cleanup(ts.Close)
+
+	return api.NewBitbucketApi(http.DefaultClient, "user", "pass", api.WithBaseUrl(ts.URL))
+}
+
+func TestServer_Permissions(t *testing.T) {
+	ba := newTestApi(t, 2)
+	ctx := context.Background()
+
+	got, err := ba.ListPermission(ctx, "myworkspace", "myrepository")
+	assert.NoError(t, err)
+	assert.Equal(t, []api.Permission{
+		{ObjectId: "administrator", ObjectName: "administrator", ObjectType: "group", PermissionType: "admin"},
+		{ObjectId: "developer", ObjectName: "developer", ObjectType: "group", PermissionType: "write"},
+		{ObjectId: "{1234-fddd-5678-a111}", ObjectName: "john-doe", ObjectType: "user", PermissionType: "admin"},
+		{ObjectId: "{9999-9999-9999-9999}", ObjectName: "reader-1", ObjectType: "user", PermissionType: "read"},
+		{ObjectId: "{aaaa-bbbb-1234-cdef}", ObjectName: "operator-1", ObjectType: "user", PermissionType: "write"},
+	}, got)
+
+	target, err := ba.ListPermission(ctx, "myworkspace", "other-repository")
+	assert.NoError(t, err)
+	assert.Empty(t, target)
+
+	err = ba.UpdatePermissions(ctx, "myworkspace", "other-repository", api.MakeOperationList(got, target))
+	assert.NoError(t, err)
+
+	copied, err := ba.ListPermission(ctx, "myworkspace", "other-repository")
+	assert.NoError(t, err)
+	assert.Equal(t, got, copied)
+
+	err = ba.UpdatePermissions(ctx, "myworkspace", "other-repository", []api.Operation{api.NewRemoveOperation(got[0])})
+	assert.NoError(t, err)
+	removed, err := ba.ListPermission(ctx, "myworkspace", "other-repository")
+	assert.NoError(t, err)
+	assert.Equal(t, got[1:], removed)
+
+	_, err = ba.ListPermission(ctx, "myworkspace", "unknown")
+	assert.Error(t, err)
+}
+
+func TestServer_DefaultReviewers(t *testing.T) {
+	ba := newTestApi(t, 0)
+	ctx := context.Background()
+
+	_, err := ba.AddDefaultReviewers(ctx, "myworkspace", "myrepository", []string{"{9999-9999-9999-9999}"})
+	assert.NoError(t, err)
+	_, err = ba.DeleteDefaultReviewers(ctx, "myworkspace", "myrepository", []string{"{1234-fddd-5678-a111}"})
+	assert.NoError(t, err)
+
+	got, err := ba.ListDefaultReviewers(ctx, "myworkspace", "myrepository")
+	assert.NoError(t, err)
+	assert.Equal(t, []api.Account{{Uuid: "{9999-9999-9999-9999}", Nickname: "reader-1", DisplayName: "reader 1"}}, got)
+}
+
+func TestServer_CurrentUser(t *testing.T) {
+	ba := newTestApi(t, 0)
+
+	got, err := ba.GetCurrentUser(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "john-doe", got.Nickname)
+	assert.True(t, got.HasScope("repository:admin"))
+}
+
+func TestServer_BranchRestrictions(t *testing.T) {
+	ba := newTestApi(t, 0)
+	ctx := context.Background()
+
+	src, err := ba.ListBranchRestrictions(ctx, "myworkspace", "myrepository")
+	assert.NoError(t, err)
+	assert.Len(t, src, 2)
+	assert.Equal(t, "john-doe", src[0].Users[0].Nickname)
+	assert.Equal(t, []string{"administrator"}, src[0].Groups)
+
+	err = ba.UpdateBranchRestrictions(ctx, "myworkspace", "other-repository", api.MakeBranchRestrictionOperationList(src, nil))
+	assert.NoError(t, err)
+	got, err := ba.ListBranchRestrictions(ctx, "myworkspace", "other-repository")
+	assert.NoError(t, err)
+	for _, v := range api.MakeBranchRestrictionOperationList(src, got) {
+		assert.True(t, v.Same(), v.Message())
+	}
+
+	err = ba.UpdateBranchRestrictions(ctx, "myworkspace", "other-repository", api.MakeBranchRestrictionOperationList(src[1:], got))
+	assert.NoError(t, err)
+	got, err = ba.ListBranchRestrictions(ctx, "myworkspace", "other-repository")
+	assert.NoError(t, err)
+	assert.Len(t, got, 1)
+	assert.Equal(t, api.BranchRestrictionRequireApprovalsToMerge, got[0].Kind)
+}
+
+func TestServer_BranchingModel(t *testing.T) {
+	ba := newTestApi(t, 0)
+	ctx := context.Background()
+
+	src, err := ba.GetBranchingModel(ctx, "myworkspace", "myrepository")
+	assert.NoError(t, err)
+	assert.Equal(t, "develop", src.Development.Name)
+
+	target, err := ba.GetBranchingModel(ctx, "myworkspace", "other-repository")
+	assert.NoError(t, err)
+	assert.True(t, target.Development.UseMainBranch)
+
+	err = ba.UpdateBranchingModel(ctx, "myworkspace", "other-repository", api.MakeSettingChanges(src.Settings(), target.Settings()))
+	assert.NoError(t, err)
+	got, err := ba.GetBranchingModel(ctx, "myworkspace", "other-repository")
+	assert.NoError(t, err)
+	assert.Equal(t, src.Settings(), got.Settings())
+}
+
+func TestServer_RepositorySettings(t *testing.T) {
+	ba := newTestApi(t, 0)
+	ctx := context.Background()
+
+	src, err := ba.GetRepositorySettings(ctx, "myworkspace", "myrepository")
+	assert.NoError(t, err)
+	assert.Equal(t, api.ForkPolicyNoPublicForks, src.ForkPolicy)
+	assert.Equal(t, "main", src.MainBranch)
+
+	target, err := ba.GetRepositorySettings(ctx, "myworkspace", "other-repository")
+	assert.NoError(t, err)
+
+	err = ba.UpdateRepositorySettings(ctx, "myworkspace", "other-repository", api.MakeSettingChanges(src.Settings(), target.Settings()))
+	assert.NoError(t, err)
+	got, err := ba.GetRepositorySettings(ctx, "myworkspace", "other-repository")
+	assert.NoError(t, err)
+	assert.Equal(t, src, got)
+}
+
+func TestServer_Webhooks(t *testing.T) {
+	ba := newTestApi(t, 0)
+	ctx := context.Background()
+
+	src, err := ba.ListWebhooks(ctx, "myworkspace", "myrepository")
+	assert.NoError(t, err)
+	assert.Len(t, src, 2)
+	assert.True(t, src[0].SecretSet)
+
+	operations := api.MakeWebhookOperationList(src, nil)
+	for i, v := range operations {
+		if v.NeedsSecret() {
+			operations[i] = v.WithSecret("other")
+		}
+	}
+	err = ba.UpdateWebhooks(ctx, "myworkspace", "other-repository", operations)
+	assert.NoError(t, err)
+	got, err := ba.ListWebhooks(ctx, "myworkspace", "other-repository")
+	assert.NoError(t, err)
+	for _, v := range api.MakeWebhookOperationList(src, got) {
+		assert.True(t, v.Same(), v.Message())
+	}
+
+	src[0].SecretSet = false
+	err = ba.UpdateWebhooks(ctx, "myworkspace", "other-repository", api.MakeWebhookOperationList(src[:1], got))
+	assert.NoError(t, err)
+	got, err = ba.ListWebhooks(ctx, "myworkspace", "other-repository")
+	assert.NoError(t, err)
+	assert.Len(t, got, 1)
+	assert.False(t, got[0].SecretSet)
+}
+
+func TestServer_Repositories(t *testing.T) {
+	ba := newTestApi(t, 1)
+	ctx := context.Background()
+
+	type repository struct {
+		Slug     string `json:"slug"`
+		FullName string `json:"full_name"`
+	}
+	list := func(opts api.PageOptions) []repository {
+		got := make([]repository, 0)
+		err := api.Paginate(ctx, ba, "/repositories/myworkspace", opts, func(v repository) bool {
+			got = append(got, v)
+			return true
+		})
+		assert.NoError(t, err)
+		return got
+	}
+
+	// a repository per page
+	assert.Equal(t, []repository{{Slug: "myrepository", FullName: "myworkspace/myrepository"}}, list(api.PageOptions{MaxPages: 1}))
+	assert.Equal(t, []repository{
+		{Slug: "myrepository", FullName: "myworkspace/myrepository"},
+		{Slug: "other-repository", FullName: "myworkspace/other-repository"},
+	}, list(api.PageOptions{}))
+
+	err := api.Paginate(ctx, ba, "/repositories/unknown", api.PageOptions{}, func(v repository) bool { return true })
+	assert.Error(t, err)
+}